@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+)
+
+// requireRole only lets through users whose role is at least role, using a
+// login session rather than an API key. The role is read from the database
+// on every request, so a demotion takes effect immediately rather than when
+// the caller's token expires.
+func (cfg *apiConfig) requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return cfg.auth.Require(auth.ScopeAccountWrite, func(w http.ResponseWriter, r *http.Request) {
+		p := auth.PrincipalFrom(r.Context())
+		access, err := cfg.dbQueries.GetUserAccess(r.Context(), p.UserID)
+		if err != nil {
+			helper.RespondWithError(w, 500, "unable to look up role", err)
+			return
+		}
+		if !auth.Authorize(p, access.Role, role) {
+			helper.RespondWithError(w, 403, "forbidden", nil)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// checkAccess rejects principals whose account is suspended, access tokens
+// whose session was logged out, and access tokens issued before the user was
+// last logged out everywhere.
+func (cfg *apiConfig) checkAccess(ctx context.Context, p auth.Principal) error {
+	access, err := cfg.dbQueries.GetUserAccess(ctx, p.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Logging out of one session revokes its refresh tokens, which is what
+	// ends it for access tokens too.
+	sessionEnded := false
+	if p.SessionID != uuid.Nil {
+		active, err := cfg.dbQueries.SessionIsActive(ctx, database.SessionIsActiveParams{
+			FamilyID: p.SessionID,
+			UserID:   p.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		sessionEnded = !active
+	}
+	return auth.CheckAccess(p, access.SuspendedAt.Valid, sessionEnded, access.TokensRevokedAt.Time)
+}
+
+// revokeAccessTokens makes every access token userID holds invalid. The time
+// is taken here rather than in the database because it is compared with the
+// tokens' iat, which comes from this clock.
+func revokeAccessTokens(ctx context.Context, q *database.Queries, userID uuid.UUID) (int64, error) {
+	return q.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{
+		ID:              userID,
+		TokensRevokedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+// AdminUser is a user as shown to admins.
+type AdminUser struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	Handle        string     `json:"handle,omitempty"`
+	Role          string     `json:"role"`
+	IsChirpyRed   bool       `json:"is_chirpy_red"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+}
+
+type adminUserPage struct {
+	Users      []AdminUser `json:"users"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// handleListUsers lists users newest first. q narrows the list to users whose
+// email or handle contains it.
+func (cfg *apiConfig) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	rows, err := cfg.dbQueries.ListUsers(r.Context(), database.ListUsersParams{
+		Query:          sql.NullString{String: q, Valid: q != ""},
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list users", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(u database.ListUsersRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: u.CreatedAt, ID: u.ID}
+	})
+
+	resp := adminUserPage{Users: make([]AdminUser, 0, len(rows)), NextCursor: nextCursor}
+	for _, u := range rows {
+		user := AdminUser{
+			ID:            u.ID,
+			CreatedAt:     u.CreatedAt,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerifiedAt.Valid,
+			Handle:        u.Handle.String,
+			Role:          u.Role,
+			IsChirpyRed:   u.IsChirpyRed,
+		}
+		if u.SuspendedAt.Valid {
+			user.SuspendedAt = &u.SuspendedAt.Time
+		}
+		resp.Users = append(resp.Users, user)
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+// targetUser parses the userID path value, refusing the caller's own ID so an
+// admin can't lock themselves out.
+func targetUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return uuid.Nil, false
+	}
+	if userID == auth.PrincipalFrom(r.Context()).UserID {
+		helper.RespondWithError(w, 400, "admins can't change their own account here", nil)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (cfg *apiConfig) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+	if !auth.ValidRole(params.Role) {
+		helper.RespondWithError(w, 400, "role must be user, moderator or admin", nil)
+		return
+	}
+
+	updated, err := cfg.dbQueries.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: params.Role,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to set role", err)
+		return
+	}
+	if updated == 0 {
+		helper.RespondWithError(w, 404, "user not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// handleSuspendUser blocks a user from logging in or using any credential
+// they hold, and ends their sessions.
+func (cfg *apiConfig) handleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to suspend user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	updated, err := qtx.SuspendUser(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to suspend user", err)
+		return
+	}
+	if updated == 0 {
+		helper.RespondWithError(w, 404, "user not found", nil)
+		return
+	}
+	if err := qtx.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to suspend user", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to suspend user", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+func (cfg *apiConfig) handleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := cfg.dbQueries.UnsuspendUser(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to unsuspend user", err)
+		return
+	}
+	if updated == 0 {
+		helper.RespondWithError(w, 404, "user not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// handleForceLogout ends every session a user has, including access tokens
+// that haven't expired yet. API keys are left alone; the user manages those.
+func (cfg *apiConfig) handleForceLogout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := targetUser(w, r)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to log out user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	updated, err := revokeAccessTokens(r.Context(), qtx, userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to log out user", err)
+		return
+	}
+	if updated == 0 {
+		helper.RespondWithError(w, 404, "user not found", nil)
+		return
+	}
+	if err := qtx.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to log out user", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to log out user", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// handleAdminDeleteChirp deletes a chirp whoever wrote it.
+func (cfg *apiConfig) handleAdminDeleteChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid chirp id", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		helper.RespondWithError(w, 404, "chirp not found", err)
+		return
+	}
+
+	if err := cfg.removeChirp(r.Context(), chirp); err != nil {
+		helper.RespondWithError(w, 500, "unable to delete", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// promoteAdmins makes the accounts in a comma-separated list of emails
+// admins, which is how the first admin gets created. Emails without an
+// account are ignored.
+func (cfg *apiConfig) promoteAdmins(ctx context.Context, list string) error {
+	emails := splitList(list)
+	for i, e := range emails {
+		emails[i] = normalizeEmail(e)
+	}
+	return cfg.dbQueries.PromoteAdmins(ctx, emails)
+}
@@ -1,15 +1,45 @@
 package main
 
 import (
+	"database/sql"
 	"sync/atomic"
+	"time"
 
+	"github.com/thetsajeet/chirpy/internal/auth"
 	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entitlements"
+	"github.com/thetsajeet/chirpy/internal/lockout"
+	"github.com/thetsajeet/chirpy/internal/mailer"
+	"github.com/thetsajeet/chirpy/internal/moderation"
+	"github.com/thetsajeet/chirpy/internal/webhook"
 )
 
 type apiConfig struct {
-	fileServerHits atomic.Int32
-	dbQueries      *database.Queries
-	PLATFORM       string
-	JWT_SECRET     string
-	POLKA_KEY      string
+	fileServerHits       atomic.Int32
+	db                   *sql.DB
+	dbQueries            *database.Queries
+	chirpFilter          moderation.Filter
+	moderationWords      *moderation.WordList
+	mailer               mailer.Mailer
+	keys                 *auth.Keyring
+	auth                 *auth.Middleware
+	passwords            *auth.Hasher
+	accountThrottle      *lockout.Limiter
+	ipThrottle           *lockout.Limiter
+	polka                *webhook.Verifier
+	webhooks             *webhook.Sender
+	entitlements         *entitlements.Config
+	PLATFORM             string
+	JWT_SECRET           string
+	JWT_SIGNING_ALG      string
+	POLKA_KEY            string
+	POLKA_ALLOW_UNSIGNED bool
+	TRUST_PROXY          bool
+	TRUSTED_PROXY_HOPS   int
+	APP_BASE_URL         string
+
+	CHIRP_EDIT_WINDOW      time.Duration
+	REFRESH_TOKEN_TTL      time.Duration
+	PASSWORD_RESET_TTL     time.Duration
+	EMAIL_VERIFICATION_TTL time.Duration
 }
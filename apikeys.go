@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+// APIKey is a personal API key as shown to its owner. Key is only set in the
+// response that creates it; afterwards the prefix is all that identifies it.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func newAPIKey(k database.ApiKey) APIKey {
+	key := APIKey{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		key.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		key.LastUsedAt = &k.LastUsedAt.Time
+	}
+	return key
+}
+
+func (cfg *apiConfig) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	caller := auth.PrincipalFrom(r.Context())
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+	if params.Name == "" {
+		helper.RespondWithError(w, 400, "name is required", nil)
+		return
+	}
+
+	scopes, err := auth.ParseScopes(params.Scopes)
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+	if len(scopes) == 0 {
+		helper.RespondWithError(w, 400, "at least one scope is required", nil)
+		return
+	}
+	// A key can't be used to mint a more powerful one.
+	for _, scope := range scopes {
+		if !caller.HasScope(scope) {
+			helper.RespondWithError(w, 403, "credential is missing scope "+scope, nil)
+			return
+		}
+	}
+
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			helper.RespondWithError(w, 400, "expires_at must be in the future", nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create api key", err)
+		return
+	}
+
+	key, err := cfg.dbQueries.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:    caller.UserID,
+		Name:      params.Name,
+		Prefix:    prefix,
+		KeyHash:   auth.HashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create api key", err)
+		return
+	}
+
+	resp := newAPIKey(key)
+	resp.Key = secret
+	helper.RespondWithJson(w, 201, resp)
+}
+
+func (cfg *apiConfig) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := cfg.dbQueries.ListAPIKeys(r.Context(), auth.PrincipalFrom(r.Context()).UserID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list api keys", err)
+		return
+	}
+
+	resp := make([]APIKey, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, newAPIKey(k))
+	}
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := uuid.Parse(r.PathValue("keyID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid keyID", err)
+		return
+	}
+
+	n, err := cfg.dbQueries.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     keyID,
+		UserID: auth.PrincipalFrom(r.Context()).UserID,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke api key", err)
+		return
+	}
+	if n == 0 {
+		helper.RespondWithError(w, 404, "api key not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
@@ -1,30 +1,75 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
-	"slices"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/chirptext"
 	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entities"
+	"github.com/thetsajeet/chirpy/internal/entitlements"
 	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/moderation"
+	"github.com/thetsajeet/chirpy/internal/pagination"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID           uuid.UUID         `json:"id"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Body         string            `json:"body"`
+	UserID       uuid.UUID         `json:"user_id"`
+	InReplyTo    *uuid.UUID        `json:"in_reply_to,omitempty"`
+	LikeCount    int32             `json:"like_count"`
+	RechirpCount int32             `json:"rechirp_count"`
+	LikedByMe    bool              `json:"liked_by_me"`
+	Edited       bool              `json:"edited"`
+	Entities     []entities.Entity `json:"entities"`
+}
+
+type ThreadNode struct {
+	Chirp
+	Deleted bool          `json:"deleted"`
+	Replies []*ThreadNode `json:"replies"`
+}
+
+const (
+	maxThreadDepth = 20
+	maxThreadNodes = 1000
+)
+
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func newChirp(c database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:           c.ID,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+		Body:         c.Body,
+		UserID:       c.UserID,
+		LikeCount:    c.LikeCount,
+		RechirpCount: c.RechirpCount,
+		Entities:     entities.Extract(c.Body),
+		Edited:       c.UpdatedAt.After(c.CreatedAt),
+	}
+	if c.ParentID.Valid {
+		chirp.InReplyTo = &c.ParentID.UUID
+	}
+	return chirp
 }
 
 func (cfg *apiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
 	}
 
 	defer r.Body.Close()
@@ -44,103 +89,235 @@ func (cfg *apiConfig) CreateChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	userId := auth.PrincipalFrom(r.Context()).UserID
+
+	author, err := cfg.dbQueries.GetUserById(r.Context(), userId)
 	if err != nil {
 		helper.RespondWithError(w, 401, "unauthorized", err)
 		return
 	}
+	if !author.EmailVerifiedAt.Valid {
+		helper.RespondWithError(w, 403, "verify your email address before posting", nil)
+		return
+	}
 
-	userId, err := auth.ValidateJWT(token, cfg.JWT_SECRET)
+	_, ent, err := cfg.entitlementsFor(r.Context(), userId)
 	if err != nil {
-		helper.RespondWithError(w, 401, "unauthorized", err)
+		helper.RespondWithError(w, 500, "Unable to create chirp", err)
+		return
+	}
+	if !cfg.checkChirpRate(w, r, userId, ent) {
 		return
 	}
 
-	if len(params.Body) > 140 {
-		helper.RespondWithError(w, 400, "Chirp is too long", nil)
+	moderated, ok := cfg.checkChirpBody(w, params.Body, ent.ChirpLength)
+	if !ok {
 		return
 	}
 
-	badWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
+	parentID := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.dbQueries.GetChirpById(r.Context(), *params.InReplyTo)
+		if err != nil || parent.DeletedAt.Valid {
+			helper.RespondWithError(w, 404, "chirp to reply to not found", err)
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
 	}
 
-	cleanedBody := getCleanedBody(params.Body, badWords)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "Unable to create chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := insertChirp(r.Context(), qtx, userId, parentID, moderated)
+	if err != nil {
+		helper.RespondWithError(w, 500, "Unable to create chirp", err)
+		return
+	}
 
-	chirp, err := cfg.dbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: userId,
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "Unable to create chirp", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 201, newChirp(chirp))
+}
+
+// insertChirp stores a chirp that has passed moderation along with its
+// entities and any moderation flags, and queues the chirp.created webhook.
+func insertChirp(ctx context.Context, q *database.Queries, userID uuid.UUID, parentID uuid.NullUUID, moderated moderation.Result) (database.Chirp, error) {
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:     moderated.Body,
+		UserID:   userID,
+		ParentID: parentID,
 	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
 
+	if err := storeChirpEntities(ctx, q, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := flagChirp(ctx, q, chirp.ID, moderated); err != nil {
+		return database.Chirp{}, err
+	}
+	if err := enqueueWebhookEvent(ctx, q, eventChirpCreated, newChirp(chirp), chirp.UserID); err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, nil
+}
+
+// checkChirpRate responds with 429 and returns false when userID has already
+// posted as many chirps in the last hour as their plan allows.
+func (cfg *apiConfig) checkChirpRate(w http.ResponseWriter, r *http.Request, userID uuid.UUID, ent entitlements.Entitlements) bool {
+	wait, err := chirpRateWait(r.Context(), cfg.dbQueries, userID, ent, time.Now().UTC())
 	if err != nil {
-		helper.RespondWithError(w, 400, "Unable to create chirp", err)
-		return
+		helper.RespondWithError(w, 500, "Unable to create chirp", err)
+		return false
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+		helper.RespondWithError(w, http.StatusTooManyRequests, "hourly chirp limit reached", nil)
+		return false
 	}
+	return true
+}
 
-	helper.RespondWithJson(w, 201, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+// chirpRateWait returns how long userID has to wait at now before their plan
+// lets them post another chirp, or zero if they can post straight away.
+func chirpRateWait(ctx context.Context, q *database.Queries, userID uuid.UUID, ent entitlements.Entitlements, now time.Time) (time.Duration, error) {
+	if ent.ChirpsPerHour == 0 {
+		return 0, nil
+	}
+
+	since := now.Add(-time.Hour)
+	recent, err := q.CountChirpsSince(ctx, database.CountChirpsSinceParams{
+		Since:  since,
+		UserID: userID,
 	})
+	if err != nil {
+		return 0, err
+	}
+	if recent.Count < int64(ent.ChirpsPerHour) {
+		return 0, nil
+	}
+	// The limit frees up when the oldest chirp in the window leaves it.
+	return recent.Oldest.Sub(since), nil
+}
+
+// checkChirpBody validates a chirp body submitted by a user against their
+// plan's length limit and runs it through the moderation filter. On failure
+// it writes the error response and returns false.
+func (cfg *apiConfig) checkChirpBody(w http.ResponseWriter, body string, limit int) (moderation.Result, bool) {
+	if err := chirptext.Validate(body, limit); err != nil {
+		helper.RespondWithErrorDetails(w, 400, "Chirp is too long", err, err)
+		return moderation.Result{}, false
+	}
+
+	moderated := cfg.chirpFilter.Check(body)
+	if moderated.Rejected {
+		helper.RespondWithError(w, 400, "Chirp contains disallowed language", nil)
+		return moderation.Result{}, false
+	}
+	return moderated, true
 }
 
-func getCleanedBody(body string, badWords map[string]struct{}) string {
-	words := strings.Split(body, " ")
-	for i, word := range words {
-		loweredWord := strings.ToLower(word)
-		if _, ok := badWords[loweredWord]; ok {
-			words[i] = "****"
+// flagChirp queues a chirp for moderator review for every word that matched a
+// flag rule.
+func flagChirp(ctx context.Context, q *database.Queries, chirpID uuid.UUID, moderated moderation.Result) error {
+	if !moderated.Flagged {
+		return nil
+	}
+
+	for _, m := range moderated.Matches {
+		if m.Action != moderation.ActionFlag {
+			continue
+		}
+		if err := q.FlagChirp(ctx, database.FlagChirpParams{
+			ChirpID: chirpID,
+			Word:    moderation.Normalize(m.Word),
+		}); err != nil {
+			return err
 		}
 	}
-	cleaned := strings.Join(words, " ")
-	return cleaned
+	return nil
 }
 
 func (cfg *apiConfig) AllChirps(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	w.Header().Set("Content-Type", "application/json")
 
-	var chirps []database.Chirp = []database.Chirp{}
-	var err error = nil
-	if author_id := r.URL.Query().Get("author_id"); len(author_id) != 0 {
-		var id uuid.UUID = uuid.UUID{}
-		id, err = uuid.Parse(author_id)
-		if err == nil {
-			chirps, err = cfg.dbQueries.GetChirpsByAuthorId(r.Context(), id)
+	query := r.URL.Query()
+	page, err := pagination.Parse(query)
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	authorID := uuid.NullUUID{}
+	if author_id := query.Get("author_id"); len(author_id) != 0 {
+		id, err := uuid.Parse(author_id)
+		if err != nil {
+			helper.RespondWithError(w, 400, "invalid author_id", err)
+			return
 		}
-	} else {
-		chirps, err = cfg.dbQueries.GetAllChirps(r.Context())
+		authorID = uuid.NullUUID{UUID: id, Valid: true}
 	}
 
+	var chirps []database.Chirp
+	if query.Get("sort") == "desc" {
+		chirps, err = cfg.dbQueries.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: page.AfterCreatedAt(),
+			AfterID:        page.AfterID(),
+			Limit:          page.FetchLimit(),
+		})
+	} else {
+		chirps, err = cfg.dbQueries.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: page.AfterCreatedAt(),
+			AfterID:        page.AfterID(),
+			Limit:          page.FetchLimit(),
+		})
+	}
 	if err != nil {
 		helper.RespondWithError(w, 400, "unable to get all chirps", err)
 		return
 	}
 
-	if r.URL.Query().Get("sort") == "desc" {
-		slices.SortFunc(chirps, func(a, b database.Chirp) int {
-			return b.CreatedAt.Compare(a.CreatedAt)
-		})
-	}
+	cfg.respondWithChirpPage(w, r, page, chirps)
+}
+
+// respondWithChirpPage writes one page of chirps fetched with
+// page.FetchLimit(), including the next cursor and the viewer's likes.
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, page pagination.Page, chirps []database.Chirp) {
+	chirps, nextCursor := pagination.Trim(page, chirps, chirpCursor)
 
-	resp := make([]Chirp, 0)
+	resp := chirpPage{Chirps: make([]Chirp, 0, len(chirps)), NextCursor: nextCursor}
 	for _, v := range chirps {
-		resp = append(resp, Chirp{
-			ID:        v.ID,
-			CreatedAt: v.CreatedAt,
-			UpdatedAt: v.UpdatedAt,
-			Body:      v.Body,
-			UserID:    v.UserID,
-		})
+		resp.Chirps = append(resp.Chirps, newChirp(v))
+	}
+
+	refs := make([]*Chirp, 0, len(resp.Chirps))
+	for i := range resp.Chirps {
+		refs = append(refs, &resp.Chirps[i])
+	}
+	if err := cfg.markLikedByMe(r.Context(), auth.PrincipalFrom(r.Context()).UserID, refs...); err != nil {
+		helper.RespondWithError(w, 500, "unable to get chirps", err)
+		return
 	}
 
 	helper.RespondWithJson(w, 200, resp)
 }
 
+func chirpCursor(c database.Chirp) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
 func (cfg *apiConfig) GetChirp(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
@@ -149,33 +326,226 @@ func (cfg *apiConfig) GetChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chirp, err := cfg.dbQueries.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		helper.RespondWithError(w, 404, "not found", err)
+		return
+	}
+
+	resp := newChirp(chirp)
+	if err := cfg.markLikedByMe(r.Context(), auth.PrincipalFrom(r.Context()).UserID, &resp); err != nil {
+		helper.RespondWithError(w, 500, "unable to get chirp", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) ChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid chirpID", err)
+		return
+	}
+
+	rootID, err := cfg.dbQueries.GetThreadRootId(r.Context(), chirpID)
 	if err != nil {
 		helper.RespondWithError(w, 404, "not found", err)
 		return
 	}
 
-	helper.RespondWithJson(w, 200, Chirp{
-		ID:        chirp.ID,
+	rows, err := cfg.dbQueries.GetThread(r.Context(), database.GetThreadParams{
+		RootID:   rootID,
+		MaxDepth: maxThreadDepth,
+		MaxNodes: maxThreadNodes,
+	})
+	if err != nil || len(rows) == 0 {
+		helper.RespondWithError(w, 500, "unable to load thread", err)
+		return
+	}
+
+	// Rows come back ordered by depth, so every parent is seen before its
+	// replies and siblings are already in chronological order.
+	nodes := make(map[uuid.UUID]*ThreadNode, len(rows))
+	refs := make([]*Chirp, 0, len(rows))
+	var root *ThreadNode
+	for _, row := range rows {
+		node := &ThreadNode{
+			Chirp: newChirp(database.Chirp{
+				ID:           row.ID,
+				CreatedAt:    row.CreatedAt,
+				UpdatedAt:    row.UpdatedAt,
+				Body:         row.Body,
+				UserID:       row.UserID,
+				ParentID:     row.ParentID,
+				LikeCount:    row.LikeCount,
+				RechirpCount: row.RechirpCount,
+			}),
+			Deleted: row.DeletedAt.Valid,
+			Replies: []*ThreadNode{},
+		}
+		if node.Deleted {
+			node.UserID = uuid.Nil
+		}
+		nodes[row.ID] = node
+		refs = append(refs, &node.Chirp)
+
+		if row.Depth == 0 {
+			root = node
+			continue
+		}
+		if parent, ok := nodes[row.ParentID.UUID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	if err := cfg.markLikedByMe(r.Context(), auth.PrincipalFrom(r.Context()).UserID, refs...); err != nil {
+		helper.RespondWithError(w, 500, "unable to load thread", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, root)
+}
+
+func (cfg *apiConfig) EditChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	userId := auth.PrincipalFrom(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid chirp id", err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode json", err)
+		return
+	}
+
+	_, ent, err := cfg.entitlementsFor(r.Context(), userId)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	moderated, ok := cfg.checkChirpBody(w, params.Body, ent.ChirpLength)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		helper.RespondWithError(w, 404, "chirp not found", err)
+		return
+	}
+
+	if chirp.UserID != userId {
+		helper.RespondWithError(w, 403, "unauthorized", nil)
+		return
+	}
+
+	if time.Since(chirp.CreatedAt) > ent.EditWindow.Duration {
+		helper.RespondWithError(w, 403, "chirp can no longer be edited", nil)
+		return
+	}
+
+	if moderated.Body == chirp.Body {
+		helper.RespondWithJson(w, 200, newChirp(chirp))
+		return
+	}
+
+	if err := qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID:   chirp.ID,
 		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
+		CreatedAt: chirp.UpdatedAt,
+	}); err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	chirp, err = qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   chirp.ID,
+		Body: moderated.Body,
 	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	if err := reindexChirpEntities(r.Context(), qtx, chirp); err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	if err := flagChirp(r.Context(), qtx, chirp.ID, moderated); err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to edit chirp", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, newChirp(chirp))
 }
 
-func (cfg *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
+func (cfg *apiConfig) ChirpHistory(w http.ResponseWriter, r *http.Request) {
+	type revision struct {
+		Body       string    `json:"body"`
+		CreatedAt  time.Time `json:"created_at"`
+		ReplacedAt time.Time `json:"replaced_at"`
+	}
+
+	type response struct {
+		Chirp     Chirp      `json:"chirp"`
+		Revisions []revision `json:"revisions"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
-		helper.RespondWithError(w, 401, "not authenticated", err)
+		helper.RespondWithError(w, 400, "invalid chirpID", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		helper.RespondWithError(w, 404, "not found", err)
 		return
 	}
 
-	userId, err := auth.ValidateJWT(token, cfg.JWT_SECRET)
+	revisions, err := cfg.dbQueries.ListChirpRevisions(r.Context(), chirpID)
 	if err != nil {
-		helper.RespondWithError(w, 401, "not authenticated", err)
+		helper.RespondWithError(w, 500, "unable to load history", err)
 		return
 	}
 
+	resp := response{Chirp: newChirp(chirp), Revisions: make([]revision, 0, len(revisions))}
+	for _, rev := range revisions {
+		resp.Revisions = append(resp.Revisions, revision{
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
+	userId := auth.PrincipalFrom(r.Context()).UserID
+
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
 		helper.RespondWithError(w, 400, "invalid chirp id", err)
@@ -183,7 +553,7 @@ func (cfg *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chirp, err := cfg.dbQueries.GetChirpById(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		helper.RespondWithError(w, 404, "chirp not found", err)
 		return
 	}
@@ -193,13 +563,54 @@ func (cfg *apiConfig) DeleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cfg.dbQueries.DeleteChirp(r.Context(), database.DeleteChirpParams{
-		UserID: userId,
-		ID:     chirpID,
-	}); err != nil {
-		helper.RespondWithError(w, 404, "unable to delete", err)
+	if err := cfg.removeChirp(r.Context(), chirp); err != nil {
+		helper.RespondWithError(w, 500, "unable to delete", err)
 		return
 	}
 
 	helper.RespondWithJson(w, 204, map[string]any{})
 }
+
+// removeChirp deletes chirp and queues the chirp.deleted webhook. Replies
+// keep pointing at a tombstone so the rest of the thread survives.
+func (cfg *apiConfig) removeChirp(ctx context.Context, chirp database.Chirp) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	// Locking the chirp makes a reply being inserted at the same time wait
+	// for the delete, or the delete wait for the reply, so a reply can't
+	// slip in after the check and be orphaned.
+	if _, err := qtx.GetChirpForUpdate(ctx, chirp.ID); err != nil {
+		return err
+	}
+
+	hasReplies, err := qtx.ChirpHasReplies(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if hasReplies {
+		err = qtx.TombstoneChirp(ctx, database.TombstoneChirpParams{
+			UserID: chirp.UserID,
+			ID:     chirp.ID,
+		})
+	} else {
+		err = qtx.DeleteChirp(ctx, database.DeleteChirpParams{
+			UserID: chirp.UserID,
+			ID:     chirp.ID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	deleted := map[string]uuid.UUID{"id": chirp.ID, "user_id": chirp.UserID}
+	if err := enqueueWebhookEvent(ctx, qtx, eventChirpDeleted, deleted, chirp.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
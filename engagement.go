@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+func (cfg *apiConfig) LikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.engage(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return cfg.dbQueries.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (cfg *apiConfig) UnlikeChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.engage(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return cfg.dbQueries.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (cfg *apiConfig) RechirpChirp(w http.ResponseWriter, r *http.Request) {
+	cfg.engage(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return cfg.dbQueries.Rechirp(ctx, database.RechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+func (cfg *apiConfig) UndoRechirp(w http.ResponseWriter, r *http.Request) {
+	cfg.engage(w, r, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		return cfg.dbQueries.UndoRechirp(ctx, database.UndoRechirpParams{UserID: userID, ChirpID: chirpID})
+	})
+}
+
+// engage checks the chirp in the path exists and applies a like/rechirp
+// change for the caller. The counters on chirps are kept in sync by
+// database triggers, so the handlers never touch them directly.
+func (cfg *apiConfig) engage(w http.ResponseWriter, r *http.Request, apply func(ctx context.Context, userID, chirpID uuid.UUID) error) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid chirpID", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		helper.RespondWithError(w, 404, "chirp not found", err)
+		return
+	}
+
+	if err := apply(r.Context(), userID, chirpID); err != nil {
+		helper.RespondWithError(w, 500, "something went wrong", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// markLikedByMe fills in LikedByMe on chirps for the given viewer.
+func (cfg *apiConfig) markLikedByMe(ctx context.Context, viewerID uuid.UUID, chirps ...*Chirp) error {
+	if viewerID == uuid.Nil || len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+
+	liked, err := cfg.dbQueries.ListLikedChirpIds(ctx, database.ListLikedChirpIdsParams{
+		UserID:   viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	likedSet := make(map[uuid.UUID]struct{}, len(liked))
+	for _, id := range liked {
+		likedSet[id] = struct{}{}
+	}
+	for _, c := range chirps {
+		_, c.LikedByMe = likedSet[c.ID]
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/entitlements"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/subscription"
+)
+
+// userPlan picks the configured plan a user is on. Chirpy Red members get
+// their subscription's plan if it is configured and the standard Chirpy Red
+// plan otherwise; everyone else gets the default plan.
+func (cfg *apiConfig) userPlan(isChirpyRed bool, subscriptionPlan sql.NullString) string {
+	if !isChirpyRed {
+		return cfg.entitlements.DefaultPlan
+	}
+	if subscriptionPlan.Valid && cfg.entitlements.Has(subscriptionPlan.String) {
+		return subscriptionPlan.String
+	}
+	return subscription.DefaultPlan
+}
+
+// entitlementsFor looks up what userID's plan currently allows.
+func (cfg *apiConfig) entitlementsFor(ctx context.Context, userID uuid.UUID) (string, entitlements.Entitlements, error) {
+	row, err := cfg.dbQueries.GetUserPlan(ctx, userID)
+	if err != nil {
+		return "", entitlements.Entitlements{}, err
+	}
+	plan := cfg.userPlan(row.IsChirpyRed, row.Plan)
+	return plan, cfg.entitlements.For(plan), nil
+}
+
+// badges returns the profile badges of userID's plan. They are decoration,
+// so a failed lookup shows none rather than failing the request.
+func (cfg *apiConfig) badges(ctx context.Context, userID uuid.UUID) []string {
+	_, ent, err := cfg.entitlementsFor(ctx, userID)
+	if err != nil {
+		log.Printf("unable to get badges for %s: %v", userID, err)
+		return []string{}
+	}
+	return ent.Badges
+}
+
+func (cfg *apiConfig) handleGetEntitlements(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Plan string `json:"plan"`
+		entitlements.Entitlements
+	}
+
+	plan, ent, err := cfg.entitlementsFor(r.Context(), auth.PrincipalFrom(r.Context()).UserID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to get entitlements", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, response{Plan: plan, Entitlements: ent})
+}
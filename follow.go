@@ -0,0 +1,254 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+)
+
+type Follow struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Handle      string    `json:"handle,omitempty"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	FollowedAt  time.Time `json:"followed_at"`
+}
+
+type TimelineItem struct {
+	ID          uuid.UUID  `json:"id"`
+	Type        string     `json:"type"`
+	ActivityAt  time.Time  `json:"activity_at"`
+	RechirpedBy *uuid.UUID `json:"rechirped_by,omitempty"`
+	Chirp       Chirp      `json:"chirp"`
+}
+
+const (
+	timelineItemChirp   = "chirp"
+	timelineItemRechirp = "rechirp"
+)
+
+type followPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handleFollow(w http.ResponseWriter, r *http.Request) {
+	followerID := auth.PrincipalFrom(r.Context()).UserID
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
+	}
+
+	if followeeID == followerID {
+		helper.RespondWithError(w, 400, "cannot follow yourself", nil)
+		return
+	}
+
+	if _, err := cfg.dbQueries.GetUserById(r.Context(), followeeID); errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 404, "user not found", err)
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to look up user", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to follow user", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	followed, err := qtx.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to follow user", err)
+		return
+	}
+
+	// Following someone already followed changes nothing, so there is no
+	// event to send.
+	if followed > 0 {
+		follow := map[string]uuid.UUID{"follower_id": followerID, "followee_id": followeeID}
+		if err := enqueueWebhookEvent(r.Context(), qtx, eventUserFollowed, follow, followerID, followeeID); err != nil {
+			helper.RespondWithError(w, 500, "unable to follow user", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to follow user", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+func (cfg *apiConfig) handleUnfollow(w http.ResponseWriter, r *http.Request) {
+	followerID := auth.PrincipalFrom(r.Context()).UserID
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
+	}
+
+	if err := cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}); err != nil {
+		helper.RespondWithError(w, 500, "unable to unfollow user", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+func (cfg *apiConfig) handleFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:         userID,
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list followers", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(f database.ListFollowersRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: f.FollowedAt, ID: f.ID}
+	})
+
+	resp := followPage{Users: make([]Follow, 0, len(rows)), NextCursor: nextCursor}
+	for _, f := range rows {
+		resp.Users = append(resp.Users, Follow{
+			UserID:      f.ID,
+			Handle:      f.Handle.String,
+			IsChirpyRed: f.IsChirpyRed,
+			FollowedAt:  f.FollowedAt,
+		})
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) handleFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:         userID,
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list following", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(f database.ListFollowingRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: f.FollowedAt, ID: f.ID}
+	})
+
+	resp := followPage{Users: make([]Follow, 0, len(rows)), NextCursor: nextCursor}
+	for _, f := range rows {
+		resp.Users = append(resp.Users, Follow{
+			UserID:      f.ID,
+			Handle:      f.Handle.String,
+			IsChirpyRed: f.IsChirpyRed,
+			FollowedAt:  f.FollowedAt,
+		})
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Items      []TimelineItem `json:"items"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:         userID,
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to load timeline", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(row database.GetTimelineRow) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: row.ActivityAt, ID: row.ItemID}
+	})
+
+	resp := response{Items: make([]TimelineItem, 0, len(rows)), NextCursor: nextCursor}
+	for _, row := range rows {
+		item := TimelineItem{
+			ID:         row.ItemID,
+			Type:       timelineItemChirp,
+			ActivityAt: row.ActivityAt,
+			Chirp:      newChirp(row.Chirp),
+		}
+		if row.RechirpedBy.Valid {
+			item.Type = timelineItemRechirp
+			item.RechirpedBy = &row.RechirpedBy.UUID
+		}
+		resp.Items = append(resp.Items, item)
+	}
+
+	refs := make([]*Chirp, 0, len(resp.Items))
+	for i := range resp.Items {
+		refs = append(refs, &resp.Items[i].Chirp)
+	}
+	if err := cfg.markLikedByMe(r.Context(), userID, refs...); err != nil {
+		helper.RespondWithError(w, 500, "unable to load timeline", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entities"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+)
+
+// storeChirpEntities indexes the hashtags and mentions in a chirp's body so
+// they can be used for the hashtag and mention feeds. Mentions of handles that
+// don't belong to anyone are ignored.
+func storeChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	ents := entities.Extract(chirp.Body)
+
+	if tags := entities.Values(ents, entities.Hashtag); len(tags) > 0 {
+		if err := q.AddChirpHashtags(ctx, database.AddChirpHashtagsParams{
+			ChirpID: chirp.ID,
+			Tags:    tags,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if handles := entities.Values(ents, entities.Mention); len(handles) > 0 {
+		if err := q.AddChirpMentions(ctx, database.AddChirpMentionsParams{
+			ChirpID: chirp.ID,
+			Handles: handles,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reindexChirpEntities replaces the stored entities of an edited chirp.
+func reindexChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.DeleteChirpHashtags(ctx, chirp.ID); err != nil {
+		return err
+	}
+	if err := q.DeleteChirpMentions(ctx, chirp.ID); err != nil {
+		return err
+	}
+	return storeChirpEntities(ctx, q, chirp)
+}
+
+func (cfg *apiConfig) HashtagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := entities.NormalizeHashtag(r.PathValue("tag"))
+	if tag == "" {
+		helper.RespondWithError(w, 400, "invalid hashtag", nil)
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.ListChirpsByHashtag(r.Context(), database.ListChirpsByHashtagParams{
+		Tag:            tag,
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to get chirps", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, page, chirps)
+}
+
+func (cfg *apiConfig) handleMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	chirps, err := cfg.dbQueries.ListChirpsMentioningUser(r.Context(), database.ListChirpsMentioningUserParams{
+		UserID:         userID,
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to get mentions", err)
+		return
+	}
+
+	cfg.respondWithChirpPage(w, r, page, chirps)
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyPrefix marks personal API keys so they can be sent as bearer tokens
+// alongside JWTs and told apart without a database lookup.
+const apiKeyPrefix = "chirpy_"
+
+// GenerateAPIKey returns a new personal API key and the short prefix shown
+// to the user to tell their keys apart. Only a hash of the key is stored.
+func GenerateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + hex.EncodeToString(b)
+	return key, key[:len(apiKeyPrefix)+6], nil
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
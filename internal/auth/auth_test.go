@@ -1,9 +1,11 @@
 package auth
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCheckPasswordHash(t *testing.T) {
@@ -61,35 +63,115 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 }
 
+func TestHasherRehash(t *testing.T) {
+	weak := Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	strong := Argon2Params{Memory: 2048, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	weakHash, _ := NewHasher(weak).Hash("hunter2")
+	strongHash, _ := NewHasher(strong).Hash("hunter2")
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+
+	tests := []struct {
+		name            string
+		hash            string
+		password        string
+		wantNeedsRehash bool
+		wantErr         bool
+	}{
+		{
+			name:     "Current parameters",
+			hash:     strongHash,
+			password: "hunter2",
+		},
+		{
+			name:            "Outdated parameters",
+			hash:            weakHash,
+			password:        "hunter2",
+			wantNeedsRehash: true,
+		},
+		{
+			name:            "Legacy bcrypt",
+			hash:            string(bcryptHash),
+			password:        "hunter2",
+			wantNeedsRehash: true,
+		},
+		{
+			name:     "Wrong password",
+			hash:     strongHash,
+			password: "hunter3",
+			wantErr:  true,
+		},
+		{
+			name:     "Wrong password for bcrypt",
+			hash:     string(bcryptHash),
+			password: "hunter3",
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown algorithm",
+			hash:     "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA",
+			password: "hunter2",
+			wantErr:  true,
+		},
+	}
+
+	h := NewHasher(strong)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			needsRehash, err := h.Verify(tt.hash, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() needsRehash = %v, want %v", needsRehash, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestHashPasswordLongPasswords(t *testing.T) {
+	// bcrypt ignores everything past 72 bytes; argon2id must not.
+	prefix := strings.Repeat("a", 72)
+	hash, err := HashPassword(prefix + "one")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$") {
+		t.Errorf("HashPassword() = %s, want an argon2id PHC string", hash)
+	}
+	if err := CheckPasswordHash(hash, prefix+"two"); err == nil {
+		t.Errorf("CheckPasswordHash() accepted a password differing after 72 bytes")
+	}
+}
+
 func TestValidateJWT(t *testing.T) {
+	k, _ := newTestKeyring(t, "", mustGenerate(t, AlgEdDSA))
+	other, _ := newTestKeyring(t, "", mustGenerate(t, AlgEdDSA))
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret")
+	validToken, _ := k.MakeJWT(userID, uuid.New())
+	foreignToken, _ := other.MakeJWT(userID, uuid.New())
 
 	tests := []struct {
 		name        string
 		tokenString string
-		tokenSecret string
 		wantUserID  uuid.UUID
 		wantErr     bool
 	}{
 		{
 			name:        "Valid token",
 			tokenString: validToken,
-			tokenSecret: "secret",
 			wantUserID:  userID,
 			wantErr:     false,
 		},
 		{
 			name:        "Invalid token",
 			tokenString: "invalid.token.string",
-			tokenSecret: "secret",
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
 		{
-			name:        "Wrong secret",
-			tokenString: validToken,
-			tokenSecret: "wrong_secret",
+			name:        "Signed with another key",
+			tokenString: foreignToken,
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
@@ -97,7 +179,7 @@ func TestValidateJWT(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, err := k.ValidateJWT(tt.tokenString)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -108,3 +190,20 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestMFATokenIsNotAccessToken(t *testing.T) {
+	k, _ := newTestKeyring(t, "", mustGenerate(t, AlgRS256))
+	userID := uuid.New()
+	mfaToken, _ := k.MakeMFAToken(userID)
+	accessToken, _ := k.MakeJWT(userID, uuid.New())
+
+	if _, err := k.ValidateJWT(mfaToken); err == nil {
+		t.Errorf("ValidateJWT() accepted an MFA challenge token")
+	}
+	if _, err := k.ValidateMFAToken(accessToken); err == nil {
+		t.Errorf("ValidateMFAToken() accepted an access token")
+	}
+	if got, err := k.ValidateMFAToken(mfaToken); err != nil || got != userID {
+		t.Errorf("ValidateMFAToken() = %v, %v, want %v", got, err, userID)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -13,49 +14,81 @@ import (
 	"github.com/google/uuid"
 )
 
-func MakeJWT(
-	userID uuid.UUID,
-	tokenSecret string,
-) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour * 1)),
-		Subject:   userID.String(),
-	})
-	return token.SignedString(signingKey)
+const (
+	accessTokenIssuer = "chirpy"
+	mfaTokenIssuer    = "chirpy-mfa"
+)
+
+const (
+	accessTokenTTL = time.Hour
+	mfaTokenTTL    = 5 * time.Minute
+)
+
+// tokenClaims are the claims in every JWT we issue. Scope is a
+// space-separated list as in RFC 8693; MFA challenge tokens have none.
+// SessionID is the session an access token was issued to.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope     string `json:"scope,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+func newClaims(userID uuid.UUID, issuer string, ttl time.Duration) tokenClaims {
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(ttl)),
+			Subject:   userID.String(),
+		},
+	}
+	if issuer == accessTokenIssuer {
+		claims.Scope = strings.Join(AllScopes, " ")
+	}
+	return claims
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
-	token, err := jwt.ParseWithClaims(
+// parseToken verifies tokenString with the key returned by keyFunc and
+// returns who it was issued to. Only the listed signing methods are
+// accepted.
+func parseToken(tokenString, wantIssuer string, keyFunc jwt.Keyfunc, methods ...string) (Principal, error) {
+	claims := tokenClaims{}
+	_, err := jwt.ParseWithClaims(
 		tokenString,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		&claims,
+		keyFunc,
+		jwt.WithValidMethods(methods),
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return Principal{}, err
 	}
 
-	userIDString, err := token.Claims.GetSubject()
-	if err != nil {
-		return uuid.Nil, err
+	if claims.Issuer != wantIssuer {
+		return Principal{}, errors.New("invalid issuer")
 	}
 
-	issuer, err := token.Claims.GetIssuer()
+	id, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
-	}
-	if issuer != "chirpy" {
-		return uuid.Nil, errors.New("invalid issuer")
+		return Principal{}, fmt.Errorf("invalid user ID: %w", err)
 	}
 
-	id, err := uuid.Parse(userIDString)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+	// Access tokens from before scopes existed carry no scope claim and
+	// had full access.
+	scopes := AllScopes
+	if claims.Scope != "" {
+		scopes = strings.Fields(claims.Scope)
+	}
+	p := Principal{UserID: id, Scopes: scopes, TokenID: claims.ID}
+	if claims.IssuedAt != nil {
+		p.IssuedAt = claims.IssuedAt.Time
 	}
-	return id, nil
+	if claims.SessionID != "" {
+		if p.SessionID, err = uuid.Parse(claims.SessionID); err != nil {
+			return Principal{}, fmt.Errorf("invalid session ID: %w", err)
+		}
+	}
+	return p, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -79,6 +112,13 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(token), nil
 }
 
+// HashToken returns the form single-use tokens such as password reset tokens
+// are stored in, so a database leak doesn't hand out working links.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	if apiKey := headers.Get("Authorization"); apiKey == "" {
 		return "", errors.New("no api key found")
@@ -0,0 +1,360 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Asymmetric algorithms access tokens can be signed with.
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const (
+	// keyringRefreshInterval is how stale the keyring may get before the
+	// next signature reloads it, so every instance picks up a rotation.
+	keyringRefreshInterval = 5 * time.Minute
+	// keyringMinReload stops tokens with made-up key IDs from turning into
+	// a reload per request.
+	keyringMinReload = 10 * time.Second
+)
+
+// RetiredKeyTTL is how long a retired key has to keep verifying: long enough
+// for every token it signed to expire, including ones signed by instances
+// that hadn't reloaded the keyring yet.
+const RetiredKeyTTL = accessTokenTTL + keyringRefreshInterval
+
+// LegacyTokenTTL is how long tokens signed with the old JWT_SECRET lived,
+// and so how long after the switch to asymmetric keys they need accepting.
+const LegacyTokenTTL = accessTokenTTL
+
+// SigningKey is a private key used to sign JWTs, identified in token headers
+// by its ID ("kid"). Retired keys no longer sign but still verify.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	CreatedAt time.Time
+	Retired   bool
+}
+
+func GenerateSigningKey(alg string) (SigningKey, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return SigningKey{}, err
+	}
+
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return SigningKey{
+		ID:        hex.EncodeToString(id),
+		Algorithm: alg,
+		Private:   private,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// MarshalPrivateKey encodes the private key as PKCS #8 DER for storage.
+func (k SigningKey) MarshalPrivateKey() ([]byte, error) {
+	return x509.MarshalPKCS8PrivateKey(k.Private)
+}
+
+// ParseSigningKey is the inverse of MarshalPrivateKey.
+func ParseSigningKey(id, alg string, der []byte, createdAt time.Time, retired bool) (SigningKey, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	var private crypto.Signer
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg != AlgRS256 {
+			return SigningKey{}, fmt.Errorf("key %s: RSA key stored as %s", id, alg)
+		}
+		private = key
+	case ed25519.PrivateKey:
+		if alg != AlgEdDSA {
+			return SigningKey{}, fmt.Errorf("key %s: Ed25519 key stored as %s", id, alg)
+		}
+		private = key
+	default:
+		return SigningKey{}, fmt.Errorf("key %s: unsupported key type %T", id, parsed)
+	}
+
+	return SigningKey{
+		ID:        id,
+		Algorithm: alg,
+		Private:   private,
+		CreatedAt: createdAt,
+		Retired:   retired,
+	}, nil
+}
+
+func (k SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// KeyLoader returns every key that should currently verify tokens, active
+// and retired.
+type KeyLoader func(ctx context.Context) ([]SigningKey, error)
+
+// Keyring signs tokens with the newest active key and verifies them with any
+// key it holds. Tokens signed with the old HS256 JWT_SECRET, which carry no
+// key ID, keep verifying until the legacy deadline; after that anyone who
+// still has the secret could mint tokens with it.
+type Keyring struct {
+	load         KeyLoader
+	legacySecret []byte
+	legacyUntil  time.Time
+	now          func() time.Time
+
+	mu       sync.RWMutex
+	active   *SigningKey
+	keys     map[string]SigningKey
+	loadedAt time.Time
+
+	reloadMu sync.Mutex
+}
+
+// NewKeyring returns a Keyring that loads its keys with load. Tokens signed
+// with legacySecret are accepted until legacyUntil.
+func NewKeyring(load KeyLoader, legacySecret string, legacyUntil time.Time) *Keyring {
+	k := &Keyring{
+		load:        load,
+		legacyUntil: legacyUntil,
+		now:         time.Now,
+		keys:        map[string]SigningKey{},
+	}
+	if legacySecret != "" {
+		k.legacySecret = []byte(legacySecret)
+	}
+	return k
+}
+
+// Reload replaces the keys with whatever the loader returns now.
+func (k *Keyring) Reload(ctx context.Context) error {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+	return k.reload(ctx)
+}
+
+func (k *Keyring) reload(ctx context.Context) error {
+	loaded, err := k.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]SigningKey, len(loaded))
+	var active *SigningKey
+	for i := range loaded {
+		key := loaded[i]
+		keys[key.ID] = key
+		if !key.Retired && (active == nil || key.CreatedAt.After(active.CreatedAt)) {
+			active = &key
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// reloadIfOlder reloads unless another caller has done so within maxAge.
+func (k *Keyring) reloadIfOlder(maxAge time.Duration) {
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+
+	k.mu.RLock()
+	fresh := time.Since(k.loadedAt) < maxAge
+	k.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// A failed reload leaves the previous keys in place, which is the best
+	// we can do; the next attempt will try again.
+	_ = k.reload(ctx)
+}
+
+// ActiveKeyID returns the ID of the key new tokens are signed with, or "" if
+// there is none.
+func (k *Keyring) ActiveKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return ""
+	}
+	return k.active.ID
+}
+
+// MakeJWT returns an access token for userID, tied to sessionID so that
+// ending the session ends the token too.
+func (k *Keyring) MakeJWT(userID, sessionID uuid.UUID) (string, error) {
+	claims := newClaims(userID, accessTokenIssuer, accessTokenTTL)
+	claims.SessionID = sessionID.String()
+	return k.sign(claims)
+}
+
+func (k *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	p, err := k.verify(tokenString, accessTokenIssuer)
+	return p.UserID, err
+}
+
+// ParseAccessToken validates an access token and returns the user and scopes
+// it grants.
+func (k *Keyring) ParseAccessToken(tokenString string) (Principal, error) {
+	return k.verify(tokenString, accessTokenIssuer)
+}
+
+func (k *Keyring) MakeMFAToken(userID uuid.UUID) (string, error) {
+	return k.sign(newClaims(userID, mfaTokenIssuer, mfaTokenTTL))
+}
+
+func (k *Keyring) ValidateMFAToken(tokenString string) (uuid.UUID, error) {
+	p, err := k.verify(tokenString, mfaTokenIssuer)
+	return p.UserID, err
+}
+
+func (k *Keyring) sign(claims tokenClaims) (string, error) {
+	k.reloadIfOlder(keyringRefreshInterval)
+
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+	if active == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(active.method(), claims)
+	token.Header["kid"] = active.ID
+	return token.SignedString(active.Private)
+}
+
+func (k *Keyring) verify(tokenString, wantIssuer string) (Principal, error) {
+	methods := []string{AlgRS256, AlgEdDSA}
+	if k.acceptsLegacy() {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return parseToken(tokenString, wantIssuer, k.keyFunc, methods...)
+}
+
+func (k *Keyring) acceptsLegacy() bool {
+	return k.legacySecret != nil && k.now().Before(k.legacyUntil)
+}
+
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if kid != "" || !k.acceptsLegacy() {
+			return nil, errors.New("unexpected HMAC-signed token")
+		}
+		return k.legacySecret, nil
+	}
+
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	key, ok := k.lookup(kid)
+	if !ok {
+		// The key may have been created by a rotation on another instance.
+		k.reloadIfOlder(keyringMinReload)
+		if key, ok = k.lookup(kid); !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("key %s is not a %s key", kid, token.Method.Alg())
+	}
+	return key.Private.Public(), nil
+}
+
+func (k *Keyring) lookup(kid string) (SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// JWK is the public half of a signing key as described in RFC 7517.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys other services need to verify our tokens.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwk := JWK{
+			Use:       "sig",
+			Algorithm: key.Algorithm,
+			KeyID:     key.ID,
+		}
+		switch pub := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newTestKeyring(t *testing.T, legacySecret string, keys ...SigningKey) (*Keyring, *[]SigningKey) {
+	t.Helper()
+	stored := append([]SigningKey{}, keys...)
+	k := NewKeyring(func(ctx context.Context) ([]SigningKey, error) {
+		return stored, nil
+	}, legacySecret, time.Now().Add(LegacyTokenTTL))
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	return k, &stored
+}
+
+func mustGenerate(t *testing.T, alg string) SigningKey {
+	t.Helper()
+	key, err := GenerateSigningKey(alg)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey(%s) error = %v", alg, err)
+	}
+	return key
+}
+
+func TestKeyringSignAndVerify(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			k, _ := newTestKeyring(t, "", mustGenerate(t, alg))
+			userID := uuid.New()
+
+			token, err := k.MakeJWT(userID, uuid.New())
+			if err != nil {
+				t.Fatalf("MakeJWT() error = %v", err)
+			}
+			got, err := k.ValidateJWT(token)
+			if err != nil || got != userID {
+				t.Errorf("ValidateJWT() = %v, %v, want %v", got, err, userID)
+			}
+			if _, err := k.ValidateMFAToken(token); err == nil {
+				t.Errorf("ValidateMFAToken() accepted an access token")
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old := mustGenerate(t, AlgRS256)
+	k, stored := newTestKeyring(t, "", old)
+	userID := uuid.New()
+
+	oldToken, _ := k.MakeJWT(userID, uuid.New())
+
+	old.Retired = true
+	*stored = []SigningKey{old, mustGenerate(t, AlgEdDSA)}
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if _, err := k.ValidateJWT(oldToken); err != nil {
+		t.Errorf("ValidateJWT() rejected a token signed by a retired key: %v", err)
+	}
+
+	newToken, _ := k.MakeJWT(userID, uuid.New())
+	if _, err := k.ValidateJWT(newToken); err != nil {
+		t.Errorf("ValidateJWT() rejected a token signed by the new key: %v", err)
+	}
+	if len(k.JWKS().Keys) != 2 {
+		t.Errorf("JWKS() returned %d keys, want 2", len(k.JWKS().Keys))
+	}
+}
+
+func TestKeyringReloadsOnUnknownKey(t *testing.T) {
+	signer, _ := newTestKeyring(t, "", mustGenerate(t, AlgEdDSA))
+	token, _ := signer.MakeJWT(uuid.New(), uuid.New())
+
+	// A second instance that hasn't seen the key yet.
+	verifier, stored := newTestKeyring(t, "")
+	verifier.loadedAt = verifier.loadedAt.Add(-keyringMinReload)
+	*stored = []SigningKey{*signer.active}
+
+	if _, err := verifier.ValidateJWT(token); err != nil {
+		t.Errorf("ValidateJWT() error = %v, want reload to find the key", err)
+	}
+}
+
+func TestKeyringLegacyTokens(t *testing.T) {
+	userID := uuid.New()
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(userID, accessTokenIssuer, accessTokenTTL)).
+		SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	k, _ := newTestKeyring(t, "secret", mustGenerate(t, AlgRS256))
+	if got, err := k.ValidateJWT(legacy); err != nil || got != userID {
+		t.Errorf("ValidateJWT() = %v, %v, want legacy token accepted", got, err)
+	}
+
+	k.now = func() time.Time { return k.legacyUntil }
+	if _, err := k.ValidateJWT(legacy); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token after the legacy deadline")
+	}
+
+	k, _ = newTestKeyring(t, "", mustGenerate(t, AlgRS256))
+	if _, err := k.ValidateJWT(legacy); err == nil {
+		t.Errorf("ValidateJWT() accepted an HS256 token without a legacy secret")
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		key := mustGenerate(t, alg)
+		der, err := key.MarshalPrivateKey()
+		if err != nil {
+			t.Fatalf("MarshalPrivateKey() error = %v", err)
+		}
+
+		parsed, err := ParseSigningKey(key.ID, alg, der, key.CreatedAt, false)
+		if err != nil {
+			t.Fatalf("ParseSigningKey() error = %v", err)
+		}
+		if parsed.Algorithm != alg || parsed.ID != key.ID {
+			t.Errorf("ParseSigningKey() = %+v", parsed)
+		}
+	}
+
+	key := mustGenerate(t, AlgRS256)
+	der, _ := key.MarshalPrivateKey()
+	if _, err := ParseSigningKey(key.ID, AlgEdDSA, der, key.CreatedAt, false); err == nil {
+		t.Errorf("ParseSigningKey() accepted an RSA key labelled EdDSA")
+	}
+}
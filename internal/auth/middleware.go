@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+const realm = "chirpy"
+
+// AuthenticateFunc resolves a bearer credential into the principal it
+// belongs to.
+type AuthenticateFunc func(ctx context.Context, token string) (Principal, error)
+
+// Middleware validates the bearer credential once per request and stores the
+// resulting Principal in the request context.
+type Middleware struct {
+	authenticate AuthenticateFunc
+}
+
+func NewMiddleware(authenticate AuthenticateFunc) *Middleware {
+	return &Middleware{authenticate: authenticate}
+}
+
+type contextKey int
+
+const principalContextKey contextKey = iota
+
+// PrincipalFrom returns the caller stored by Middleware. Anonymous requests
+// get the zero Principal, whose UserID is uuid.Nil.
+func PrincipalFrom(ctx context.Context) Principal {
+	p, _ := ctx.Value(principalContextKey).(Principal)
+	return p
+}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey, p)
+}
+
+var errNoCredentials = errors.New("no credentials")
+
+// Require rejects requests without a valid credential granting scope.
+func (m *Middleware) Require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := m.principal(r)
+		if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		if !p.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope", scope=%q`, realm, scope))
+			helper.RespondWithError(w, http.StatusForbidden, "insufficient_scope", nil)
+			return
+		}
+		next(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	}
+}
+
+// Optional lets anonymous requests through, but a credential that is
+// present must be valid.
+func (m *Middleware) Optional(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := m.principal(r)
+		if errors.Is(err, errNoCredentials) {
+			next(w, r)
+			return
+		} else if err != nil {
+			unauthorized(w, err)
+			return
+		}
+		next(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	}
+}
+
+func (m *Middleware) principal(r *http.Request) (Principal, error) {
+	if r.Header.Get("Authorization") == "" {
+		return Principal{}, errNoCredentials
+	}
+
+	token, err := GetBearerToken(r.Header)
+	if err != nil {
+		return Principal{}, err
+	}
+	return m.authenticate(r.Context(), token)
+}
+
+// unauthorized writes the 401 described in RFC 6750: a bare challenge when
+// no credentials were sent, invalid_token when they were rejected.
+func unauthorized(w http.ResponseWriter, err error) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if !errors.Is(err, errNoCredentials) {
+		challenge += `, error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	helper.RespondWithError(w, http.StatusUnauthorized, "unauthorized", err)
+}
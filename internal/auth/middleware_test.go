@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	userID := uuid.New()
+	m := NewMiddleware(func(ctx context.Context, token string) (Principal, error) {
+		switch token {
+		case "full":
+			return Principal{UserID: userID, Scopes: AllScopes}, nil
+		case "read-only":
+			return Principal{UserID: userID, Scopes: []string{ScopeChirpsRead}}, nil
+		}
+		return Principal{}, errors.New("bad token")
+	})
+
+	var seen Principal
+	next := func(w http.ResponseWriter, r *http.Request) {
+		seen = PrincipalFrom(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		header     string
+		wantStatus int
+		wantAuth   string
+		wantUser   uuid.UUID
+	}{
+		{
+			name:       "Required with valid token",
+			handler:    m.Require(ScopeChirpsWrite, next),
+			header:     "Bearer full",
+			wantStatus: http.StatusNoContent,
+			wantUser:   userID,
+		},
+		{
+			name:       "Required without token",
+			handler:    m.Require(ScopeChirpsWrite, next),
+			wantStatus: http.StatusUnauthorized,
+			wantAuth:   `Bearer realm="chirpy"`,
+		},
+		{
+			name:       "Required with invalid token",
+			handler:    m.Require(ScopeChirpsWrite, next),
+			header:     "Bearer nope",
+			wantStatus: http.StatusUnauthorized,
+			wantAuth:   `error="invalid_token"`,
+		},
+		{
+			name:       "Required with missing scope",
+			handler:    m.Require(ScopeChirpsWrite, next),
+			header:     "Bearer read-only",
+			wantStatus: http.StatusForbidden,
+			wantAuth:   `error="insufficient_scope", scope="chirps:write"`,
+		},
+		{
+			name:       "Optional without token",
+			handler:    m.Optional(next),
+			wantStatus: http.StatusNoContent,
+			wantUser:   uuid.Nil,
+		},
+		{
+			name:       "Optional with valid token",
+			handler:    m.Optional(next),
+			header:     "Bearer read-only",
+			wantStatus: http.StatusNoContent,
+			wantUser:   userID,
+		},
+		{
+			name:       "Optional with invalid token",
+			handler:    m.Optional(next),
+			header:     "Bearer nope",
+			wantStatus: http.StatusUnauthorized,
+			wantAuth:   `error="invalid_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = Principal{}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			tt.handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.wantAuth) {
+				t.Errorf("WWW-Authenticate = %q, want it to contain %q", got, tt.wantAuth)
+			}
+			if seen.UserID != tt.wantUser {
+				t.Errorf("principal = %v, want %v", seen.UserID, tt.wantUser)
+			}
+		})
+	}
+}
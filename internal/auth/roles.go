@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// Roles a user can have. Each role can do everything the ones before it
+// can.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRank = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+var (
+	ErrAccountSuspended = errors.New("account suspended")
+	ErrTokenRevoked     = errors.New("token has been revoked")
+)
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Authorize reports whether p may do something that needs the want role,
+// given the role its user has now. Roles only apply to login sessions: an
+// API key acts as a plain user whatever its owner's role, so a leaked key
+// can't be used to run the site.
+func Authorize(p Principal, role, want string) bool {
+	if p.APIKey && roleRank[want] > roleRank[RoleUser] {
+		return false
+	}
+	return roleRank[role] >= roleRank[want]
+}
+
+// CheckAccess rejects principals whose account is suspended, access tokens
+// whose session has ended, and access tokens issued before tokensRevokedAt,
+// when the user was last logged out everywhere. A zero tokensRevokedAt
+// means they never were.
+func CheckAccess(p Principal, suspended, sessionEnded bool, tokensRevokedAt time.Time) error {
+	if suspended {
+		return ErrAccountSuspended
+	}
+	if sessionEnded {
+		return ErrTokenRevoked
+	}
+	// iat only has second precision, so a token issued in the same second
+	// as the logout can't be told apart from one issued just before it and
+	// is rejected too. Logging in again a second later works.
+	if !tokensRevokedAt.IsZero() && !p.IssuedAt.IsZero() &&
+		!p.IssuedAt.After(tokensRevokedAt.Truncate(time.Second)) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAuthorize(t *testing.T) {
+	session := Principal{UserID: uuid.New(), Scopes: AllScopes, IssuedAt: time.Now()}
+	apiKey := Principal{UserID: uuid.New(), Scopes: AllScopes, APIKey: true}
+
+	tests := []struct {
+		name string
+		p    Principal
+		role string
+		want string
+		ok   bool
+	}{
+		{name: "Admin session", p: session, role: RoleAdmin, want: RoleAdmin, ok: true},
+		{name: "Admin acting as moderator", p: session, role: RoleAdmin, want: RoleModerator, ok: true},
+		{name: "Moderator needing admin", p: session, role: RoleModerator, want: RoleAdmin},
+		{name: "User needing moderator", p: session, role: RoleUser, want: RoleModerator},
+		{name: "Unknown role", p: session, role: "root", want: RoleModerator},
+		{name: "Admin's API key", p: apiKey, role: RoleAdmin, want: RoleAdmin},
+		{name: "Moderator's API key", p: apiKey, role: RoleModerator, want: RoleModerator},
+		{name: "API key as a plain user", p: apiKey, role: RoleAdmin, want: RoleUser, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Authorize(tt.p, tt.role, tt.want); got != tt.ok {
+				t.Errorf("Authorize(%q, %q) = %v, want %v", tt.role, tt.want, got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	revokedAt := time.Date(2025, 3, 1, 12, 0, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name         string
+		p            Principal
+		suspended    bool
+		sessionEnded bool
+		revokedAt    time.Time
+		wantErr      error
+	}{
+		{
+			name: "Never logged out",
+			p:    Principal{IssuedAt: revokedAt.Add(-time.Hour)},
+		},
+		{
+			name:      "Suspended",
+			p:         Principal{IssuedAt: revokedAt.Add(time.Hour)},
+			suspended: true,
+			wantErr:   ErrAccountSuspended,
+		},
+		{
+			name:         "Session logged out",
+			p:            Principal{IssuedAt: revokedAt.Add(time.Hour)},
+			sessionEnded: true,
+			wantErr:      ErrTokenRevoked,
+		},
+		{
+			name:      "Issued before the logout",
+			p:         Principal{IssuedAt: revokedAt.Add(-time.Second)},
+			revokedAt: revokedAt,
+			wantErr:   ErrTokenRevoked,
+		},
+		{
+			name:      "Issued in the same second as the logout",
+			p:         Principal{IssuedAt: revokedAt.Truncate(time.Second)},
+			revokedAt: revokedAt,
+			wantErr:   ErrTokenRevoked,
+		},
+		{
+			name:      "Issued in the second after the logout",
+			p:         Principal{IssuedAt: revokedAt.Truncate(time.Second).Add(time.Second)},
+			revokedAt: revokedAt,
+		},
+		{
+			name:      "Issued after the logout",
+			p:         Principal{IssuedAt: revokedAt.Add(time.Minute)},
+			revokedAt: revokedAt,
+		},
+		{
+			name:      "API keys are revoked separately",
+			p:         Principal{APIKey: true},
+			revokedAt: revokedAt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAccess(tt.p, tt.suspended, tt.sessionEnded, tt.revokedAt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckAccess() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
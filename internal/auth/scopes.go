@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes limit what a credential may do. Access tokens from login get all of
+// them; personal API keys get whatever the user picked.
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeFollowsWrite = "follows:write"
+	ScopeAccountRead  = "account:read"
+	ScopeAccountWrite = "account:write"
+)
+
+var AllScopes = []string{
+	ScopeChirpsRead,
+	ScopeChirpsWrite,
+	ScopeFollowsWrite,
+	ScopeAccountRead,
+	ScopeAccountWrite,
+}
+
+// Principal is the user a request is authenticated as and the scopes its
+// credential grants. TokenID identifies the credential itself: the jti of an
+// access token or the ID of an API key. IssuedAt is zero for API keys, and
+// SessionID is the session an access token belongs to; it is nil for API
+// keys and for access tokens from before tokens carried one.
+type Principal struct {
+	UserID    uuid.UUID
+	Scopes    []string
+	TokenID   string
+	IssuedAt  time.Time
+	SessionID uuid.UUID
+	APIKey    bool
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// ParseScopes checks that every scope is known and returns them sorted and
+// without duplicates.
+func ParseScopes(scopes []string) ([]string, error) {
+	parsed := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !slices.Contains(AllScopes, s) {
+			return nil, fmt.Errorf("unknown scope %q", s)
+		}
+		parsed = append(parsed, s)
+	}
+	slices.Sort(parsed)
+	return slices.Compact(parsed), nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "Sorted and deduplicated",
+			scopes: []string{ScopeChirpsWrite, ScopeChirpsRead, ScopeChirpsWrite},
+			want:   []string{ScopeChirpsRead, ScopeChirpsWrite},
+		},
+		{
+			name:   "Empty",
+			scopes: nil,
+			want:   []string{},
+		},
+		{
+			name:    "Unknown scope",
+			scopes:  []string{"admin"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("ParseScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	k := NewKeyring(func(ctx context.Context) ([]SigningKey, error) {
+		key, err := GenerateSigningKey(AlgEdDSA)
+		return []SigningKey{key}, err
+	}, "", time.Time{})
+	if err := k.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	sessionID := uuid.New()
+	token, _ := k.MakeJWT(uuid.New(), sessionID)
+	p, err := k.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken() error = %v", err)
+	}
+	if p.SessionID != sessionID {
+		t.Errorf("ParseAccessToken() SessionID = %v, want %v", p.SessionID, sessionID)
+	}
+	for _, scope := range AllScopes {
+		if !p.HasScope(scope) {
+			t.Errorf("access token is missing scope %s", scope)
+		}
+	}
+	if time.Since(p.IssuedAt) > time.Minute {
+		t.Errorf("IssuedAt = %v, want about now", p.IssuedAt)
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error = %v", err)
+	}
+	if !IsAPIKey(key) || len(prefix) >= len(key) || key[:len(prefix)] != prefix {
+		t.Errorf("GenerateAPIKey() = %q, %q", key, prefix)
+	}
+	if IsAPIKey("eyJhbGciOiJSUzI1NiJ9.e30.sig") {
+		t.Errorf("IsAPIKey() accepted a JWT")
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B (SHA-1), truncated to six digits.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := TOTPCode(rfcSecret, now)
+	previous, _ := TOTPCode(rfcSecret, now.Add(-30*time.Second))
+	stale, _ := TOTPCode(rfcSecret, now.Add(-90*time.Second))
+
+	tests := []struct {
+		name   string
+		code   string
+		wantOk bool
+	}{
+		{name: "Current code", code: code, wantOk: true},
+		{name: "Previous period", code: previous, wantOk: true},
+		{name: "Too old", code: stale, wantOk: false},
+		{name: "Garbage", code: "abcdef", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfcSecret, tt.code, now); ok != tt.wantOk {
+				t.Errorf("ValidateTOTP() ok = %v, want %v", ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("ABC", "me@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:me@example.com?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("TOTPURI() = %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("GenerateRecoveryCodes() returned %d codes, want 10", len(codes))
+	}
+
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+strings.ToUpper(codes[0])+" ") {
+		t.Errorf("HashRecoveryCode() is not normalizing input")
+	}
+	if HashRecoveryCode(codes[0]) == HashRecoveryCode(codes[1]) {
+		t.Errorf("HashRecoveryCode() returned the same hash for different codes")
+	}
+}
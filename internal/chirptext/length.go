@@ -0,0 +1,78 @@
+package chirptext
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// MaxLength is the default number of characters a chirp may contain.
+	MaxLength = 140
+	// URLLength is how many characters a link counts as, however long it
+	// really is, so people aren't punished for long URLs.
+	URLLength = 23
+	// MaxBytesPerCharacter is how many bytes each character of the limit
+	// allows for, enough for an emoji with a skin tone.
+	MaxBytesPerCharacter = 8
+	// MaxURLBytes is how many bytes each link a chirp could fit allows for.
+	MaxURLBytes = 2048
+)
+
+var urlRe = regexp.MustCompile(`https?://[^\s]+`)
+
+type LengthError struct {
+	Length int `json:"length"`
+	Limit  int `json:"limit"`
+}
+
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("chirp is %d characters long, the limit is %d", e.Length, e.Limit)
+}
+
+type SizeError struct {
+	Bytes int `json:"bytes"`
+	Limit int `json:"byte_limit"`
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("chirp is %d bytes long, the limit is %d", e.Bytes, e.Limit)
+}
+
+// MaxBytes is the most bytes a chirp of limit characters may take up.
+// Length alone doesn't bound the size: links count the same however long
+// they are, and a single character can stack any number of combining marks.
+func MaxBytes(limit int) int {
+	return limit*MaxBytesPerCharacter + limit/URLLength*MaxURLBytes
+}
+
+// Length counts the user-perceived characters (grapheme clusters) in body,
+// so "é" written with a combining accent or a family emoji both count as one.
+// Every URL counts as URLLength characters.
+func Length(body string) int {
+	n := 0
+	last := 0
+	for _, loc := range urlRe.FindAllStringIndex(body, -1) {
+		start, end := loc[0], loc[1]
+		// Trailing punctuation is almost always part of the sentence, not the link.
+		end = start + len(strings.TrimRight(body[start:end], ".,;:!?)'\""))
+
+		n += uniseg.GraphemeClusterCount(body[last:start]) + URLLength
+		last = end
+	}
+	return n + uniseg.GraphemeClusterCount(body[last:])
+}
+
+// Validate returns a *LengthError when body is longer than limit, or a
+// *SizeError when it takes up more than MaxBytes(limit).
+func Validate(body string, limit int) error {
+	if n := len(body); n > MaxBytes(limit) {
+		return &SizeError{Bytes: n, Limit: MaxBytes(limit)}
+	}
+	if n := Length(body); n > limit {
+		return &LengthError{Length: n, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package chirptext
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "ASCII", body: "hello world", want: 11},
+		{name: "Emoji count once", body: strings.Repeat("😀", 50), want: 50},
+		{name: "Combining accent", body: "é", want: 1},
+		{name: "Family emoji", body: "👨‍👩‍👧‍👦", want: 1},
+		{name: "Flag", body: "🇳🇱", want: 1},
+		{name: "URL is fixed length", body: "see https://example.com/" + strings.Repeat("a", 100), want: 4 + URLLength},
+		{name: "Trailing punctuation after URL", body: "https://example.com.", want: URLLength + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.body); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(strings.Repeat("😀", MaxLength), MaxLength); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+
+	err := Validate(strings.Repeat("a", MaxLength+1), MaxLength)
+	var lengthErr *LengthError
+	if !errors.As(err, &lengthErr) {
+		t.Fatalf("Validate() error = %v, want *LengthError", err)
+	}
+	if lengthErr.Length != MaxLength+1 || lengthErr.Limit != MaxLength {
+		t.Errorf("Validate() = %+v, want length %d limit %d", lengthErr, MaxLength+1, MaxLength)
+	}
+}
+
+func TestValidateSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "Long link", body: "see https://example.com/" + strings.Repeat("a", 1000)},
+		{name: "Link as long as the whole allowance", body: "https://example.com/" + strings.Repeat("a", MaxBytes(MaxLength)), wantErr: true},
+		{name: "Stacked combining marks", body: "a" + strings.Repeat("\u0301", MaxBytes(MaxLength)), wantErr: true},
+		{name: "Emoji with skin tones", body: strings.Repeat("👍🏽", MaxLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.body, MaxLength)
+			var sizeErr *SizeError
+			if errors.As(err, &sizeErr) != tt.wantErr {
+				t.Errorf("Validate() error = %v, want size error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
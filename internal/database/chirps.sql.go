@@ -7,25 +7,66 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+select exists (
+    select 1
+    from chirps
+    where parent_id = $1
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, parentID uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, parentID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const countChirpsSince = `-- name: CountChirpsSince :one
+select count(*) as count, coalesce(min(created_at), $1)::timestamp as oldest
+from chirps
+where user_id = $2 and created_at > $1
+`
+
+type CountChirpsSinceParams struct {
+	Since  time.Time
+	UserID uuid.UUID
+}
+
+type CountChirpsSinceRow struct {
+	Count  int64
+	Oldest time.Time
+}
+
+func (q *Queries) CountChirpsSince(ctx context.Context, arg CountChirpsSinceParams) (CountChirpsSinceRow, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsSince, arg.Since, arg.UserID)
+	var i CountChirpsSinceRow
+	err := row.Scan(&i.Count, &i.Oldest)
+	return i, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-insert into chirps (id, created_at, updated_at, body, user_id)
+insert into chirps (id, created_at, updated_at, body, user_id, parent_id)
 values (
-    gen_random_uuid(), now(), now(), $1, $2
+    gen_random_uuid(), now(), now(), $1, $2, $3
 )
-returning id, created_at, updated_at, body, user_id
+returning id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.ParentID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,10 +74,30 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
 	)
 	return i, err
 }
 
+const createChirpRevision = `-- name: CreateChirpRevision :exec
+insert into chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+values (gen_random_uuid(), $1, $2, $3, now())
+`
+
+type CreateChirpRevisionParams struct {
+	ChirpID   uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateChirpRevision(ctx context.Context, arg CreateChirpRevisionParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpRevision, arg.ChirpID, arg.Body, arg.CreatedAt)
+	return err
+}
+
 const deleteChirp = `-- name: DeleteChirp :exec
 delete from chirps
 where id = $1 and user_id = $2
@@ -52,27 +113,109 @@ func (q *Queries) DeleteChirp(ctx context.Context, arg DeleteChirpParams) error
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-select id, created_at, updated_at, body, user_id 
+const getChirpById = `-- name: GetChirpById :one
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
 from chirps
-order by created_at
+where id = $1
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpById, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
+from chirps
+where id = $1
+for update
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
+
+const getThread = `-- name: GetThread :many
+with recursive thread as (
+    select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count, 0 as depth
+    from chirps
+    where chirps.id = $1
+    union all
+    select c.id, c.created_at, c.updated_at, c.body, c.user_id, c.parent_id, c.deleted_at, c.like_count, c.rechirp_count, thread.depth + 1
+    from chirps c
+    join thread on c.parent_id = thread.id
+    where thread.depth < $2::int
+)
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count, depth
+from thread
+order by depth, created_at, id
+limit $3
+`
+
+type GetThreadParams struct {
+	RootID   uuid.UUID
+	MaxDepth int32
+	MaxNodes int32
+}
+
+type GetThreadRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	ParentID     uuid.NullUUID
+	DeletedAt    sql.NullTime
+	LikeCount    int32
+	RechirpCount int32
+	Depth        int32
+}
+
+func (q *Queries) GetThread(ctx context.Context, arg GetThreadParams) ([]GetThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, arg.RootID, arg.MaxDepth, arg.MaxNodes)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetThreadRow
 	for rows.Next() {
-		var i Chirp
+		var i GetThreadRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.Depth,
 		); err != nil {
 			return nil, err
 		}
@@ -87,34 +230,145 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpById = `-- name: GetChirpById :one
-select id, created_at, updated_at, body, user_id
+const getThreadRootId = `-- name: GetThreadRootId :one
+with recursive ancestors as (
+    select id, parent_id, 0 as depth
+    from chirps
+    where chirps.id = $1
+    union all
+    select c.id, c.parent_id, ancestors.depth + 1
+    from chirps c
+    join ancestors on c.id = ancestors.parent_id
+)
+select id
+from ancestors
+order by depth desc
+limit 1
+`
+
+func (q *Queries) GetThreadRootId(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getThreadRootId, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const listChirpRevisions = `-- name: ListChirpRevisions :many
+select id, chirp_id, body, created_at, replaced_at
+from chirp_revisions
+where chirp_id = $1
+order by created_at desc, id desc
+`
+
+func (q *Queries) ListChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpRevisions, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpRevision
+	for rows.Next() {
+		var i ChirpRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChirpID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.ReplacedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
 from chirps
-where id = $1
+where deleted_at is null
+  and ($1::uuid is null or user_id = $1)
+  and ($2::timestamp is null
+       or (created_at, id) > ($2, $3::uuid))
+order by created_at, id
+limit $4
 `
 
-func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpById, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
+type ListChirpsAscParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	Limit          int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
 	)
-	return i, err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getChirpsByAuthorId = `-- name: GetChirpsByAuthorId :many
-select id, created_at, updated_at, body, user_id
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
 from chirps
-where user_id = $1
-order by created_at
+where deleted_at is null
+  and ($1::uuid is null or user_id = $1)
+  and ($2::timestamp is null
+       or (created_at, id) < ($2, $3::uuid))
+order by created_at desc, id desc
+limit $4
 `
 
-func (q *Queries) GetChirpsByAuthorId(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorId, userID)
+type ListChirpsDescParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	Limit          int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +382,10 @@ func (q *Queries) GetChirpsByAuthorId(ctx context.Context, userID uuid.UUID) ([]
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
 		); err != nil {
 			return nil, err
 		}
@@ -141,3 +399,48 @@ func (q *Queries) GetChirpsByAuthorId(ctx context.Context, userID uuid.UUID) ([]
 	}
 	return items, nil
 }
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+update chirps
+set body = '', deleted_at = now(), updated_at = now()
+where id = $1 and user_id = $2
+`
+
+type TombstoneChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) TombstoneChirp(ctx context.Context, arg TombstoneChirpParams) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, arg.ID, arg.UserID)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+update chirps
+set body = $2, updated_at = now()
+where id = $1
+returning id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
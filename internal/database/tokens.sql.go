@@ -7,13 +7,61 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const listSessions = `-- name: ListSessions :many
+select family_id, user_agent, ip, last_used_at, expires_at,
+       (select min(f.created_at) from refresh_tokens f where f.family_id = refresh_tokens.family_id)::timestamp as started_at
+from refresh_tokens
+where user_id = $1 and revoked_at is null and expires_at > now()
+order by last_used_at desc
+`
+
+type ListSessionsRow struct {
+	FamilyID   uuid.UUID
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	StartedAt  time.Time
+}
+
+func (q *Queries) ListSessions(ctx context.Context, userID uuid.UUID) ([]ListSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSessionsRow
+	for rows.Next() {
+		var i ListSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.Ip,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.StartedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const lookupToken = `-- name: LookupToken :one
-select token, created_at, updated_at, user_id, expires_at, revoked_at
+select token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip, last_used_at
 from refresh_tokens
 where token = $1
 `
@@ -28,10 +76,45 @@ func (q *Queries) LookupToken(ctx context.Context, token string) (RefreshToken,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
+const revokeAllUserTokens = `-- name: RevokeAllUserTokens :exec
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where user_id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllUserTokens, userID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where family_id = $1 and user_id = $2 and revoked_at is null
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeToken = `-- name: RevokeToken :exec
 update refresh_tokens
 set updated_at = now(), revoked_at = now()
@@ -43,18 +126,91 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) error {
 	return err
 }
 
+const revokeTokenFamily = `-- name: RevokeTokenFamily :exec
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where family_id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeTokenFamily, familyID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+update refresh_tokens
+set updated_at = now(), revoked_at = now(), replaced_by = $2
+where token = $1 and revoked_at is null and expires_at > now()
+returning token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip, last_used_at
+`
+
+type RotateRefreshTokenParams struct {
+	Token      string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, arg.Token, arg.ReplacedBy)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const sessionIsActive = `-- name: SessionIsActive :one
+select exists (
+    select 1
+    from refresh_tokens
+    where family_id = $1 and user_id = $2 and revoked_at is null
+)
+`
+
+type SessionIsActiveParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+// A session stays active while any token in its family is unrevoked.
+func (q *Queries) SessionIsActive(ctx context.Context, arg SessionIsActiveParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, sessionIsActive, arg.FamilyID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const storeRefreshToken = `-- name: StoreRefreshToken :exec
-insert into refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
-values ($1, now(), now(), $2, $3, null)
+insert into refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip, last_used_at)
+values ($1, now(), now(), $2, $3, null, $4, $5, $6, now())
 `
 
 type StoreRefreshTokenParams struct {
 	Token     string
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	FamilyID  uuid.UUID
+	UserAgent string
+	Ip        string
 }
 
 func (q *Queries) StoreRefreshToken(ctx context.Context, arg StoreRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, storeRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, storeRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.Ip,
+	)
 	return err
 }
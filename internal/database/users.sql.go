@@ -7,34 +7,39 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password)
+insert into users (id, created_at, updated_at, email, hashed_password, handle)
 values (
-    gen_random_uuid(), now(), now(), $1, $2
+    gen_random_uuid(), now(), now(), $1, $2, $3
 )
-returning id, created_at, updated_at, email, is_chirpy_red
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 type CreateUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Email       string
-	IsChirpyRed bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	EmailVerifiedAt sql.NullTime
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -42,6 +47,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -55,8 +62,141 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const getUserAccess = `-- name: GetUserAccess :one
+select role, suspended_at, tokens_revoked_at
+from users
+where id = $1
+`
+
+type GetUserAccessRow struct {
+	Role            string
+	SuspendedAt     sql.NullTime
+	TokensRevokedAt sql.NullTime
+}
+
+func (q *Queries) GetUserAccess(ctx context.Context, id uuid.UUID) (GetUserAccessRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserAccess, id)
+	var i GetUserAccessRow
+	err := row.Scan(&i.Role, &i.SuspendedAt, &i.TokensRevokedAt)
+	return i, err
+}
+
+const getUserById = `-- name: GetUserById :one
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, role, suspended_at, tokens_revoked_at
+from users
+where id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const getUserPlan = `-- name: GetUserPlan :one
+select users.is_chirpy_red, subscriptions.plan
+from users
+left join subscriptions on subscriptions.user_id = users.id
+where users.id = $1
+`
+
+type GetUserPlanRow struct {
+	IsChirpyRed bool
+	Plan        sql.NullString
+}
+
+func (q *Queries) GetUserPlan(ctx context.Context, id uuid.UUID) (GetUserPlanRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserPlan, id)
+	var i GetUserPlanRow
+	err := row.Scan(&i.IsChirpyRed, &i.Plan)
+	return i, err
+}
+
+const listUsers = `-- name: ListUsers :many
+select id, created_at, email, handle, role, is_chirpy_red, email_verified_at, suspended_at
+from users
+where ($1::text is null
+       or strpos(email, lower($1)) > 0
+       or strpos(lower(handle), lower($1)) > 0)
+  and ($2::timestamp is null
+       or (created_at, id) < ($2, $3::uuid))
+order by created_at desc, id desc
+limit $4
+`
+
+type ListUsersParams struct {
+	Query          sql.NullString
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	Limit          int32
+}
+
+type ListUsersRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	Email           string
+	Handle          sql.NullString
+	Role            string
+	IsChirpyRed     bool
+	EmailVerifiedAt sql.NullTime
+	SuspendedAt     sql.NullTime
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Query,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersRow
+	for rows.Next() {
+		var i ListUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Email,
+			&i.Handle,
+			&i.Role,
+			&i.IsChirpyRed,
+			&i.EmailVerifiedAt,
+			&i.SuspendedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const loginUser = `-- name: LoginUser :one
-select id, created_at, updated_at, email, hashed_password, is_chirpy_red
+select id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, totp_secret, totp_enabled_at, totp_last_step, email_verified_at, role, suspended_at, tokens_revoked_at
 from users
 where email = $1
 `
@@ -71,44 +211,184 @@ func (q *Queries) LoginUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.EmailVerifiedAt,
+		&i.Role,
+		&i.SuspendedAt,
+		&i.TokensRevokedAt,
 	)
 	return i, err
 }
 
-const updateChirpyRed = `-- name: UpdateChirpyRed :exec
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+update users
+set email_verified_at = now(), updated_at = now()
+where id = $1 and email = $2 and email_verified_at is null
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const promoteAdmins = `-- name: PromoteAdmins :exec
+update users
+set role = 'admin', updated_at = now()
+where email = any($1::text[]) and role <> 'admin'
+`
+
+func (q *Queries) PromoteAdmins(ctx context.Context, emails []string) error {
+	_, err := q.db.ExecContext(ctx, promoteAdmins, pq.Array(emails))
+	return err
+}
+
+const revokeUserAccessTokens = `-- name: RevokeUserAccessTokens :execrows
+update users
+set tokens_revoked_at = $2
+where id = $1
+`
+
+type RevokeUserAccessTokensParams struct {
+	ID              uuid.UUID
+	TokensRevokedAt sql.NullTime
+}
+
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserAccessTokens, arg.ID, arg.TokensRevokedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+update users
+set role = $2, updated_at = now()
+where id = $1
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.ID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :execrows
+update users
+set suspended_at = coalesce(suspended_at, now()), updated_at = now()
+where id = $1
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :execrows
+update users
+set suspended_at = null, updated_at = now()
+where id = $1
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsuspendUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateChirpyRed = `-- name: UpdateChirpyRed :execrows
+update users
+set is_chirpy_red = $2, updated_at = now()
+where id = $1
+`
+
+type UpdateChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
+}
+
+func (q *Queries) UpdateChirpyRed(ctx context.Context, arg UpdateChirpyRedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateChirpyRed, arg.ID, arg.IsChirpyRed)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updatePassword = `-- name: UpdatePassword :exec
 update users
-set is_chirpy_red = true
+set hashed_password = $2, updated_at = now()
 where id = $1
 `
 
-func (q *Queries) UpdateChirpyRed(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, updateChirpyRed, id)
+type UpdatePasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdatePassword(ctx context.Context, arg UpdatePasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updatePassword, arg.ID, arg.HashedPassword)
 	return err
 }
 
 const updateUser = `-- name: UpdateUser :one
 update users
-set email = $1, hashed_password = $2, updated_at = now()
-where id = $3
-returning id, created_at, updated_at, email, is_chirpy_red
+set email = $1,
+    hashed_password = $2,
+    handle = coalesce($3, handle),
+    email_verified_at = case when email = $1 then email_verified_at end,
+    updated_at = now()
+where id = $4
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at
 `
 
 type UpdateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 	ID             uuid.UUID
 }
 
 type UpdateUserRow struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Email       string
-	IsChirpyRed bool
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	IsChirpyRed     bool
+	Handle          sql.NullString
+	EmailVerifiedAt sql.NullTime
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.Email, arg.HashedPassword, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Handle,
+		arg.ID,
+	)
 	var i UpdateUserRow
 	err := row.Scan(
 		&i.ID,
@@ -116,6 +396,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 		&i.UpdatedAt,
 		&i.Email,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,143 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Type string
+
+const (
+	Hashtag Type = "hashtag"
+	Mention Type = "mention"
+)
+
+const (
+	maxHashtagLength = 100
+	maxHandleLength  = 15
+)
+
+var handleRe = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+
+// Entity is a #hashtag or @mention found in a chirp body. Offsets are given in
+// both bytes and runes so clients in any language can slice the body.
+type Entity struct {
+	Type      Type   `json:"type"`
+	Text      string `json:"text"`
+	Value     string `json:"value"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	RuneStart int    `json:"rune_start"`
+	RuneEnd   int    `json:"rune_end"`
+}
+
+// Extract finds hashtags and mentions in body, in the order they appear.
+// A sigil only starts an entity at the beginning of the body or after a
+// character that can't be part of a word, so e-mail addresses and things like
+// "C#" are left alone.
+func Extract(body string) []Entity {
+	ents := []Entity{}
+	runeIdx := 0
+	prev := rune(-1)
+
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+
+		if (r == '#' || r == '@') && !isWordRune(prev) {
+			if ent, ok := scan(body, i, runeIdx, r); ok {
+				ents = append(ents, ent)
+				runeIdx = ent.RuneEnd
+				prev = 'x'
+				i = ent.End
+				continue
+			}
+		}
+
+		prev = r
+		runeIdx++
+		i += size
+	}
+	return ents
+}
+
+func scan(body string, start, runeStart int, sigil rune) (Entity, bool) {
+	end := start + 1
+	runes := 0
+	hasLetter := false
+	for end < len(body) {
+		r, size := utf8.DecodeRuneInString(body[end:])
+		if sigil == '@' && !isHandleRune(r) || sigil == '#' && !isWordRune(r) {
+			break
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+		end += size
+		runes++
+	}
+
+	ent := Entity{
+		Text:      body[start:end],
+		Value:     strings.ToLower(body[start+1 : end]),
+		Start:     start,
+		End:       end,
+		RuneStart: runeStart,
+		RuneEnd:   runeStart + 1 + runes,
+	}
+
+	switch sigil {
+	case '#':
+		if !hasLetter || runes > maxHashtagLength {
+			return Entity{}, false
+		}
+		ent.Type = Hashtag
+	case '@':
+		if runes == 0 || runes > maxHandleLength {
+			return Entity{}, false
+		}
+		ent.Type = Mention
+	}
+	return ent, true
+}
+
+// Values returns the distinct normalized values of entities of type t.
+func Values(ents []Entity, t Type) []string {
+	seen := map[string]struct{}{}
+	values := []string{}
+	for _, e := range ents {
+		if e.Type != t {
+			continue
+		}
+		if _, ok := seen[e.Value]; ok {
+			continue
+		}
+		seen[e.Value] = struct{}{}
+		values = append(values, e.Value)
+	}
+	return values
+}
+
+// NormalizeHandle validates a user handle and returns its canonical form.
+// A leading @ is accepted and stripped.
+func NormalizeHandle(handle string) (string, bool) {
+	handle = strings.TrimPrefix(strings.TrimSpace(handle), "@")
+	if !handleRe.MatchString(handle) {
+		return "", false
+	}
+	return strings.ToLower(handle), true
+}
+
+// NormalizeHashtag returns the canonical form of a tag as used in URLs.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
@@ -0,0 +1,78 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "Hashtag and mention",
+			body: "hi @Alice, #GoLang!",
+			want: []Entity{
+				{Type: Mention, Text: "@Alice", Value: "alice", Start: 3, End: 9, RuneStart: 3, RuneEnd: 9},
+				{Type: Hashtag, Text: "#GoLang", Value: "golang", Start: 11, End: 18, RuneStart: 11, RuneEnd: 18},
+			},
+		},
+		{
+			name: "Offsets after multi-byte runes",
+			body: "😀 #café",
+			want: []Entity{
+				{Type: Hashtag, Text: "#café", Value: "café", Start: 5, End: 11, RuneStart: 2, RuneEnd: 7},
+			},
+		},
+		{
+			name: "Email and C# are not entities",
+			body: "mail me@example.com about C# and #123",
+			want: []Entity{},
+		},
+		{
+			name: "Handle too long",
+			body: "@abcdefghijklmnopqrstuvwxyz",
+			want: []Entity{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Extract(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValues(t *testing.T) {
+	got := Values(Extract("#go #Go @bob #rust"), Hashtag)
+	want := []string{"go", "rust"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeHandle(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   string
+		wantOk bool
+	}{
+		{handle: "@Gopher_42", want: "gopher_42", wantOk: true},
+		{handle: "gopher", want: "gopher", wantOk: true},
+		{handle: "", wantOk: false},
+		{handle: "no spaces", wantOk: false},
+		{handle: "émile", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeHandle(tt.handle)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("NormalizeHandle(%q) = (%q, %v), want (%q, %v)", tt.handle, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package entitlements maps subscription plans to what they let a user do.
+// The mapping is configuration, so plans can be changed or added without a
+// code change.
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// FreePlan is the plan of users without a subscription in the built-in
+// configuration.
+const FreePlan = "free"
+
+// Entitlements are the limits and extras one plan grants.
+type Entitlements struct {
+	// ChirpLength is the most characters a chirp may have.
+	ChirpLength int `json:"chirp_length"`
+	// EditWindow is how long after posting a chirp can still be edited.
+	EditWindow Duration `json:"edit_window"`
+	// ChirpsPerHour caps how many chirps can be posted in any hour. Zero
+	// means no cap.
+	ChirpsPerHour int `json:"chirps_per_hour"`
+	// ScheduledChirps is how many chirps can be waiting to be published at
+	// once. Zero means the plan can't schedule chirps.
+	ScheduledChirps int `json:"scheduled_chirps"`
+	// Badges are shown on the user's profile.
+	Badges []string `json:"badges"`
+}
+
+// Config is the full plan to entitlements mapping. Users on a plan it
+// doesn't list get DefaultPlan.
+type Config struct {
+	DefaultPlan string                  `json:"default_plan"`
+	Plans       map[string]Entitlements `json:"plans"`
+}
+
+// Default is used when no configuration file is given.
+func Default() *Config {
+	return &Config{
+		DefaultPlan: FreePlan,
+		Plans: map[string]Entitlements{
+			FreePlan: {
+				ChirpLength:   140,
+				EditWindow:    Duration{15 * time.Minute},
+				ChirpsPerHour: 30,
+				Badges:        []string{},
+			},
+			"chirpy_red": {
+				ChirpLength:     500,
+				EditWindow:      Duration{time.Hour},
+				ChirpsPerHour:   300,
+				ScheduledChirps: 50,
+				Badges:          []string{"chirpy_red"},
+			},
+		},
+	}
+}
+
+// Has reports whether plan is configured.
+func (c *Config) Has(plan string) bool {
+	_, ok := c.Plans[plan]
+	return ok
+}
+
+// For returns the entitlements of plan, falling back to DefaultPlan.
+func (c *Config) For(plan string) Entitlements {
+	if e, ok := c.Plans[plan]; ok {
+		return e
+	}
+	return c.Plans[c.DefaultPlan]
+}
+
+func (c *Config) validate() error {
+	if len(c.Plans) == 0 {
+		return errors.New("no plans configured")
+	}
+	if !c.Has(c.DefaultPlan) {
+		return fmt.Errorf("default plan %q is not configured", c.DefaultPlan)
+	}
+	for name, e := range c.Plans {
+		if e.ChirpLength <= 0 {
+			return fmt.Errorf("plan %q: chirp_length must be positive", name)
+		}
+		if e.EditWindow.Duration < 0 || e.ChirpsPerHour < 0 || e.ScheduledChirps < 0 {
+			return fmt.Errorf("plan %q: limits can't be negative", name)
+		}
+		if e.Badges == nil {
+			e.Badges = []string{}
+			c.Plans[name] = e
+		}
+		if slices.Contains(e.Badges, "") {
+			return fmt.Errorf("plan %q: badges can't be empty", name)
+		}
+	}
+	return nil
+}
+
+// Parse reads a JSON configuration.
+func Parse(r io.Reader) (*Config, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func LoadFile(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Duration is a time.Duration written as a string such as "15m" in JSON.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
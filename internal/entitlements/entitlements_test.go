@@ -0,0 +1,105 @@
+package entitlements
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name: "Valid",
+			config: `{
+				"default_plan": "free",
+				"plans": {
+					"free": {"chirp_length": 140, "edit_window": "15m"},
+					"pro": {"chirp_length": 1000, "edit_window": "2h", "chirps_per_hour": 10, "scheduled_chirps": 5, "badges": ["pro"]}
+				}
+			}`,
+		},
+		{
+			name:    "Default plan missing",
+			config:  `{"default_plan": "basic", "plans": {"free": {"chirp_length": 140}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "No plans",
+			config:  `{"default_plan": "free", "plans": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Zero chirp length",
+			config:  `{"default_plan": "free", "plans": {"free": {"chirp_length": 0}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Negative limit",
+			config:  `{"default_plan": "free", "plans": {"free": {"chirp_length": 140, "chirps_per_hour": -1}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Bad duration",
+			config:  `{"default_plan": "free", "plans": {"free": {"chirp_length": 140, "edit_window": 900}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown field",
+			config:  `{"default_plan": "free", "plans": {"free": {"chirp_lenght": 140}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	c, err := Parse(strings.NewReader(`{
+		"default_plan": "free",
+		"plans": {
+			"free": {"chirp_length": 140, "edit_window": "15m"},
+			"pro": {"chirp_length": 1000, "edit_window": "2h", "badges": ["pro"]}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got := c.For("pro"); got.ChirpLength != 1000 || got.EditWindow.Duration != 2*time.Hour {
+		t.Errorf("For(pro) = %+v", got)
+	}
+	if got := c.For("unknown"); got.ChirpLength != 140 {
+		t.Errorf("For(unknown) = %+v, want the default plan", got)
+	}
+	if got := c.For("free"); got.Badges == nil {
+		t.Errorf("For(free).Badges = nil, want an empty list")
+	}
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().validate(); err != nil {
+		t.Errorf("Default() is invalid: %v", err)
+	}
+}
+
+func TestExampleFileMatchesDefault(t *testing.T) {
+	c, err := LoadFile("../../entitlements.example.json")
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("entitlements.example.json = %+v, want it to match Default() %+v", c, Default())
+	}
+}
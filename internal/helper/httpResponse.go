@@ -7,6 +7,12 @@ import (
 )
 
 func RespondWithError(w http.ResponseWriter, code int, msg string, err error) {
+	RespondWithErrorDetails(w, code, msg, nil, err)
+}
+
+// RespondWithErrorDetails is RespondWithError with extra machine-readable
+// information about the failure under "details".
+func RespondWithErrorDetails(w http.ResponseWriter, code int, msg string, details any, err error) {
 	if err != nil {
 		log.Println(err)
 	}
@@ -16,11 +22,13 @@ func RespondWithError(w http.ResponseWriter, code int, msg string, err error) {
 	}
 
 	type errorResponse struct {
-		Error string `json:"error"`
+		Error   string `json:"error"`
+		Details any    `json:"details,omitempty"`
 	}
 
 	RespondWithJson(w, code, errorResponse{
-		Error: msg,
+		Error:   msg,
+		Details: details,
 	})
 }
 
@@ -0,0 +1,160 @@
+// Package lockout throttles repeated failures, such as wrong passwords, with
+// exponential backoff and temporary lockouts.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Policy decides how failures for one kind of key are punished.
+type Policy struct {
+	// FreeAttempts failures are allowed before any delay kicks in.
+	FreeAttempts int
+	// BaseDelay is the wait after the first failure past FreeAttempts. It
+	// doubles with every further failure, up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutAfter failures lock the key for LockoutFor. The failure count
+	// starts over once the lockout is in place.
+	LockoutAfter int
+	LockoutFor   time.Duration
+	// Window is how long a failure is remembered.
+	Window time.Duration
+}
+
+// State is what a Store remembers about a key.
+type State struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store keeps failure counts.
+type Store interface {
+	// Get returns the state of key, or the zero State if there is none.
+	Get(ctx context.Context, key string) (State, error)
+	// Update replaces the state of key with what update returns and
+	// returns the new state. It must be atomic, since concurrent attempts
+	// for the same key are exactly what is being limited. Keys whose last
+	// failure was before forgetBefore and that aren't locked at now may be
+	// dropped along the way.
+	Update(ctx context.Context, key string, now, forgetBefore time.Time, update func(State) State) (State, error)
+	Reset(ctx context.Context, key string) error
+}
+
+type Limiter struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func New(store Store, policy Policy) *Limiter {
+	return &Limiter{store: store, policy: policy, now: time.Now}
+}
+
+// Attempt is an attempt reserved with Limiter.Attempt.
+type Attempt struct {
+	// RetryAfter is how long the caller has to wait before trying again.
+	// If it isn't zero the attempt was refused and nothing was reserved.
+	RetryAfter time.Duration
+	// Locks reports that the attempt used up the last one before a
+	// lockout, so the key is locked unless it is released.
+	Locks bool
+}
+
+// Check returns how long the caller has to wait before key may try again.
+// Zero means go ahead.
+func (l *Limiter) Check(ctx context.Context, key string) (time.Duration, error) {
+	st, err := l.store.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return l.retryAfter(st, l.now()), nil
+}
+
+// Attempt reserves an attempt for key before the caller finds out whether
+// it fails. The reservation counts as a failure straight away, so that a
+// burst of parallel guesses can't all get in before the first of them
+// fails; call Success or Release if it doesn't.
+func (l *Limiter) Attempt(ctx context.Context, key string) (Attempt, error) {
+	now := l.now()
+	forgetBefore := now.Add(-l.policy.Window)
+
+	var a Attempt
+	_, err := l.store.Update(ctx, key, now, forgetBefore, func(st State) State {
+		a = Attempt{RetryAfter: l.retryAfter(st, now)}
+		if a.RetryAfter > 0 {
+			return st
+		}
+
+		if st.LastFailureAt.Before(forgetBefore) {
+			st.Failures = 0
+		}
+		st.Failures++
+		st.LastFailureAt = now
+		if l.policy.LockoutAfter > 0 && st.Failures >= l.policy.LockoutAfter {
+			// The failure count starts over once the lockout is in place.
+			st.Failures = 0
+			st.LockedUntil = now.Add(l.policy.LockoutFor)
+			a.Locks = true
+		}
+		return st
+	})
+	if err != nil {
+		return Attempt{}, err
+	}
+	return a, nil
+}
+
+// Failure reports how long key has to wait after the reserved attempt a
+// failed, and whether a put it in lockout, which callers will usually want
+// to audit.
+func (l *Limiter) Failure(ctx context.Context, key string, a Attempt) (retryAfter time.Duration, locked bool, err error) {
+	if a.Locks {
+		return l.policy.LockoutFor, true, nil
+	}
+	wait, err := l.Check(ctx, key)
+	return wait, false, err
+}
+
+// Release gives back the reserved attempt a, which turned out not to be a
+// failure. Use it for keys that a success shouldn't otherwise forgive.
+func (l *Limiter) Release(ctx context.Context, key string, a Attempt) error {
+	now := l.now()
+	_, err := l.store.Update(ctx, key, now, now.Add(-l.policy.Window), func(st State) State {
+		if a.Locks && !st.LockedUntil.IsZero() {
+			st.LockedUntil = time.Time{}
+			st.Failures = l.policy.LockoutAfter - 1
+		} else if st.Failures > 0 {
+			st.Failures--
+		}
+		return st
+	})
+	return err
+}
+
+// Success forgets the failures for key.
+func (l *Limiter) Success(ctx context.Context, key string) error {
+	return l.store.Reset(ctx, key)
+}
+
+func (l *Limiter) retryAfter(st State, now time.Time) time.Duration {
+	if now.Before(st.LockedUntil) {
+		return st.LockedUntil.Sub(now)
+	}
+	if st.Failures <= l.policy.FreeAttempts || now.Sub(st.LastFailureAt) > l.policy.Window {
+		return 0
+	}
+
+	delay := l.policy.BaseDelay
+	for i := l.policy.FreeAttempts + 1; i < st.Failures && delay < l.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, l.policy.MaxDelay)
+
+	if wait := st.LastFailureAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts: 2,
+	BaseDelay:    time.Second,
+	MaxDelay:     8 * time.Second,
+	LockoutAfter: 8,
+	LockoutFor:   time.Minute,
+	Window:       time.Hour,
+}
+
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(NewMemoryStore(), testPolicy)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+// fail reserves an attempt for key and reports it failed.
+func fail(t *testing.T, l *Limiter, key string) (time.Duration, bool) {
+	t.Helper()
+	ctx := context.Background()
+	a, err := l.Attempt(ctx, key)
+	if err != nil {
+		t.Fatalf("Attempt() error = %v", err)
+	}
+	if a.RetryAfter > 0 {
+		t.Fatalf("Attempt() refused, retry after %v", a.RetryAfter)
+	}
+	wait, locked, err := l.Failure(ctx, key, a)
+	if err != nil {
+		t.Fatalf("Failure() error = %v", err)
+	}
+	return wait, locked
+}
+
+func TestBackoff(t *testing.T) {
+	l, now := newTestLimiter()
+
+	// Each entry is the wait after that many failures.
+	want := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second}
+	for i, w := range want {
+		got, locked := fail(t, l, "account:a@example.com")
+		if locked {
+			t.Fatalf("Failure() locked after %d failures", i+1)
+		}
+		if got != w {
+			t.Errorf("after %d failures retryAfter = %v, want %v", i+1, got, w)
+		}
+		*now = now.Add(got)
+	}
+}
+
+func TestAttemptRefusedWhileWaiting(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+	key := "account:a@example.com"
+
+	for i := 0; i <= testPolicy.FreeAttempts; i++ {
+		fail(t, l, key)
+	}
+	a, err := l.Attempt(ctx, key)
+	if err != nil {
+		t.Fatalf("Attempt() error = %v", err)
+	}
+	if a.RetryAfter != time.Second {
+		t.Errorf("Attempt() RetryAfter = %v, want %v", a.RetryAfter, time.Second)
+	}
+
+	// A refused attempt isn't counted.
+	st, _ := l.store.Get(ctx, key)
+	if st.Failures != testPolicy.FreeAttempts+1 {
+		t.Errorf("failures = %d, want %d", st.Failures, testPolicy.FreeAttempts+1)
+	}
+}
+
+func TestParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter()
+	key := "account:a@example.com"
+
+	// None of the attempts has failed yet, but only as many get in as
+	// would have if they had been made one after another.
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a, err := l.Attempt(ctx, key)
+			if err == nil && a.RetryAfter == 0 {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := reserved.Load(); got != int32(testPolicy.FreeAttempts+1) {
+		t.Errorf("%d attempts reserved, want %d", got, testPolicy.FreeAttempts+1)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+	key := "account:a@example.com"
+
+	var wait time.Duration
+	var locked bool
+	for i := 0; i < testPolicy.LockoutAfter; i++ {
+		if locked {
+			t.Fatalf("Failure() locked after %d failures", i)
+		}
+		*now = now.Add(wait)
+		wait, locked = fail(t, l, key)
+	}
+	if !locked {
+		t.Fatalf("Failure() did not lock after %d failures", testPolicy.LockoutAfter)
+	}
+
+	if wait, _ := l.Check(ctx, key); wait != testPolicy.LockoutFor {
+		t.Errorf("Check() = %v, want %v", wait, testPolicy.LockoutFor)
+	}
+	if a, _ := l.Attempt(ctx, key); a.RetryAfter != testPolicy.LockoutFor {
+		t.Errorf("Attempt() while locked RetryAfter = %v, want %v", a.RetryAfter, testPolicy.LockoutFor)
+	}
+
+	*now = now.Add(testPolicy.LockoutFor)
+	if wait, _ := l.Check(ctx, key); wait != 0 {
+		t.Errorf("Check() after lockout = %v, want 0", wait)
+	}
+
+	if wait, _ := l.Check(ctx, "account:b@example.com"); wait != 0 {
+		t.Errorf("Check() for another key = %v, want 0", wait)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+	key := "ip:203.0.113.7"
+
+	a, _ := l.Attempt(ctx, key)
+	if err := l.Release(ctx, key, a); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if st, _ := l.store.Get(ctx, key); st.Failures != 0 {
+		t.Errorf("failures after release = %d, want 0", st.Failures)
+	}
+
+	// Releasing the attempt that locked the key unlocks it again.
+	var wait time.Duration
+	for i := 0; i < testPolicy.LockoutAfter-1; i++ {
+		*now = now.Add(wait)
+		wait, _ = fail(t, l, key)
+	}
+	*now = now.Add(wait)
+	a, _ = l.Attempt(ctx, key)
+	if !a.Locks {
+		t.Fatalf("Attempt() Locks = false after %d failures", testPolicy.LockoutAfter-1)
+	}
+	if err := l.Release(ctx, key, a); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	st, _ := l.store.Get(ctx, key)
+	if !st.LockedUntil.IsZero() || st.Failures != testPolicy.LockoutAfter-1 {
+		t.Errorf("state after release = %+v, want unlocked with %d failures", st, testPolicy.LockoutAfter-1)
+	}
+}
+
+func TestSuccessAndWindow(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter()
+	key := "ip:203.0.113.7"
+
+	var wait time.Duration
+	for i := 0; i < 4; i++ {
+		*now = now.Add(wait)
+		wait, _ = fail(t, l, key)
+	}
+	if wait, _ := l.Check(ctx, key); wait == 0 {
+		t.Fatalf("Check() = 0 after 4 failures")
+	}
+
+	if err := l.Success(ctx, key); err != nil {
+		t.Fatalf("Success() error = %v", err)
+	}
+	if wait, _ := l.Check(ctx, key); wait != 0 {
+		t.Errorf("Check() after success = %v, want 0", wait)
+	}
+
+	wait = 0
+	for i := 0; i < 4; i++ {
+		*now = now.Add(wait)
+		wait, _ = fail(t, l, key)
+	}
+	*now = now.Add(testPolicy.Window + time.Second)
+	if wait, _ := fail(t, l, key); wait != 0 {
+		t.Errorf("Failure() after the window = %v, want old failures forgotten", wait)
+	}
+}
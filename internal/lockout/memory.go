@@ -0,0 +1,60 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps failures in process memory. It is only suitable when a
+// single instance serves all logins.
+type MemoryStore struct {
+	mu        sync.Mutex
+	states    map[string]State
+	lastSweep time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: map[string]State{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.states[key], nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, now, forgetBefore time.Time, update func(State) State) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now, forgetBefore)
+
+	st := update(s.states[key])
+	s.states[key] = st
+	return st, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// sweep drops keys with nothing left to remember so that guessing random
+// emails can't grow the map forever.
+func (s *MemoryStore) sweep(now, forgetBefore time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, st := range s.states {
+		if st.LastFailureAt.Before(forgetBefore) && !now.Before(st.LockedUntil) {
+			delete(s.states, key)
+		}
+	}
+}
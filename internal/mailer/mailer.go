@@ -0,0 +1,101 @@
+// Package mailer sends transactional email such as password reset links.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTP sends mail through an SMTP relay, using STARTTLS when the server
+// offers it.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP returns an SMTP mailer. Authentication is skipped when username is
+// empty, which suits local relays such as MailHog.
+func NewSMTP(host, port, username, password, from string) *SMTP {
+	var a smtp.Auth
+	if username != "" {
+		a = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTP{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+		auth: a,
+	}
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	data := format(m.from, msg, time.Now())
+
+	// net/smtp has no context support, so run it aside and give up waiting
+	// when the context ends.
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Log writes every message to w instead of delivering it. It is meant for
+// local development and tests.
+type Log struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLog(w io.Writer) *Log {
+	return &Log{w: w}
+}
+
+func (m *Log) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\n\n%s\n---\n", msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+// format builds an RFC 5322 message. Header values are stripped of line
+// breaks so a crafted address or subject can't inject headers.
+func format(from string, msg Message, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", stripNewlines(from))
+	fmt.Fprintf(&b, "To: %s\r\n", stripNewlines(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", stripNewlines(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
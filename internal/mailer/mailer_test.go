@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLog(&buf)
+
+	err := m.Send(context.Background(), Message{
+		To:      "me@example.com",
+		Subject: "Reset your password",
+		Body:    "https://chirpy.example/reset?token=abc",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{"To: me@example.com", "Subject: Reset your password", "token=abc"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Send() wrote %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     Message
+		want    string
+		notWant string
+	}{
+		{
+			name: "Headers and body",
+			msg:  Message{To: "me@example.com", Subject: "Hi", Body: "line one\nline two"},
+			want: "To: me@example.com\r\nSubject: Hi\r\n",
+		},
+		{
+			name: "CRLF body",
+			msg:  Message{To: "me@example.com", Subject: "Hi", Body: "line one\nline two"},
+			want: "\r\n\r\nline one\r\nline two",
+		},
+		{
+			name:    "Header injection",
+			msg:     Message{To: "me@example.com", Subject: "Hi\r\nBcc: evil@example.com", Body: ""},
+			notWant: "\r\nBcc:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(format("chirpy@example.com", tt.msg, time.Unix(0, 0)))
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("format() = %q, want it to contain %q", got, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Errorf("format() = %q, must not contain %q", got, tt.notWant)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Action string
+
+const (
+	// ActionMask replaces the word with asterisks.
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole chirp.
+	ActionReject Action = "reject"
+	// ActionFlag lets the chirp through unchanged but queues it for review.
+	ActionFlag Action = "flag"
+)
+
+const mask = "****"
+
+func (a Action) Valid() bool {
+	return a == ActionMask || a == ActionReject || a == ActionFlag
+}
+
+type Rule struct {
+	Word   string `json:"word"`
+	Action Action `json:"action"`
+}
+
+type Match struct {
+	Word   string `json:"word"`
+	Action Action `json:"action"`
+}
+
+type Result struct {
+	// Body is the input with every masked word replaced.
+	Body     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+type Filter interface {
+	Check(body string) Result
+}
+
+// WordList is a Filter backed by a list of words, each with its own action.
+// Words are compared after Unicode normalization and case folding, so
+// "Kerfuffle!", "KERFUFFLE" and "ｋｅｒｆｕｆｆｌｅ" all match "kerfuffle".
+// It is safe for concurrent use and can be swapped out at runtime.
+type WordList struct {
+	mu    sync.RWMutex
+	rules map[string]Action
+}
+
+func NewWordList(rules []Rule) *WordList {
+	wl := &WordList{}
+	wl.Replace(rules)
+	return wl
+}
+
+// Replace swaps in a new set of rules.
+func (wl *WordList) Replace(rules []Rule) {
+	m := make(map[string]Action, len(rules))
+	for _, r := range rules {
+		if w := Normalize(r.Word); w != "" {
+			m[w] = r.Action
+		}
+	}
+
+	wl.mu.Lock()
+	wl.rules = m
+	wl.mu.Unlock()
+}
+
+func (wl *WordList) Rules() []Rule {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(wl.rules))
+	for w, a := range wl.rules {
+		rules = append(rules, Rule{Word: w, Action: a})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Word < rules[j].Word })
+	return rules
+}
+
+func (wl *WordList) Check(body string) Result {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+
+	res := Result{}
+	var b strings.Builder
+	last := 0
+
+	for _, w := range words(body) {
+		action, ok := wl.rules[Normalize(body[w.start:w.end])]
+		if !ok {
+			continue
+		}
+
+		res.Matches = append(res.Matches, Match{Word: body[w.start:w.end], Action: action})
+		switch action {
+		case ActionReject:
+			res.Rejected = true
+		case ActionFlag:
+			res.Flagged = true
+		case ActionMask:
+			b.WriteString(body[last:w.start])
+			b.WriteString(mask)
+			last = w.end
+		}
+	}
+	b.WriteString(body[last:])
+
+	res.Body = b.String()
+	return res
+}
+
+// Normalize folds a word to the form used for matching: compatibility
+// decomposition, combining marks removed, then case folded.
+func Normalize(word string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFKC, cases.Fold())
+	out, _, err := transform.String(t, strings.TrimSpace(word))
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(word))
+	}
+	return out
+}
+
+type span struct {
+	start, end int
+}
+
+// words returns the byte ranges of runs of letters, digits and combining
+// marks in s; everything else separates words.
+func words(s string) []span {
+	var spans []span
+	start := -1
+	for i, r := range s {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(s)})
+	}
+	return spans
+}
+
+// LoadFile reads rules from a text file with one word per line, optionally
+// followed by an action:
+//
+//	# comments and blank lines are ignored
+//	kerfuffle
+//	fornax reject
+//	sharbert flag
+//
+// Words without an action are masked.
+func LoadFile(path string) ([]Rule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		rule := Rule{Word: fields[0], Action: ActionMask}
+		if len(fields) > 1 {
+			rule.Action = Action(fields[1])
+		}
+		if len(fields) > 2 || !rule.Action.Valid() {
+			return nil, fmt.Errorf("%s:%d: expected \"word [mask|reject|flag]\"", path, line)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWordListCheck(t *testing.T) {
+	wl := NewWordList([]Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "fornax", Action: ActionMask},
+		{Word: "sharbert", Action: ActionFlag},
+		{Word: "blorp", Action: ActionReject},
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		wantBody     string
+		wantRejected bool
+		wantFlagged  bool
+	}{
+		{
+			name:     "Clean",
+			body:     "I had something interesting for breakfast",
+			wantBody: "I had something interesting for breakfast",
+		},
+		{
+			name:     "Punctuation around words",
+			body:     "What a Kerfuffle! fornax, again",
+			wantBody: "What a ****! ****, again",
+		},
+		{
+			name:     "Full-width and accented forms",
+			body:     "ｋｅｒｆｕｆｆｌｅ and fórnax",
+			wantBody: "**** and ****",
+		},
+		{
+			name:     "Substrings are not matched",
+			body:     "kerfuffles",
+			wantBody: "kerfuffles",
+		},
+		{
+			name:        "Flag",
+			body:        "Sharbert time",
+			wantBody:    "Sharbert time",
+			wantFlagged: true,
+		},
+		{
+			name:         "Reject",
+			body:         "blorp.",
+			wantBody:     "blorp.",
+			wantRejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wl.Check(tt.body)
+			if got.Body != tt.wantBody {
+				t.Errorf("Check() body = %q, want %q", got.Body, tt.wantBody)
+			}
+			if got.Rejected != tt.wantRejected {
+				t.Errorf("Check() rejected = %v, want %v", got.Rejected, tt.wantRejected)
+			}
+			if got.Flagged != tt.wantFlagged {
+				t.Errorf("Check() flagged = %v, want %v", got.Flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# bad words\nkerfuffle\n\nfornax reject\nsharbert flag\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	want := []Rule{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "fornax", Action: ActionReject},
+		{Word: "sharbert", Action: ActionFlag},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadFile() = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(path, []byte("kerfuffle explode\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("LoadFile() accepted an unknown action")
+	}
+}
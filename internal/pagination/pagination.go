@@ -0,0 +1,183 @@
+package pagination
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Cursor is a keyset position: the (created_at, id) of the last item a client
+// has already seen. Key breaks ties for lists where (created_at, id) isn't
+// unique on its own.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Key       string
+}
+
+// Encode returns the opaque string form of the cursor handed to clients.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID.String()
+	if c.Key != "" {
+		raw += "," + c.Key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func Decode(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	createdAt, rest, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, key, _ := strings.Cut(rest, ",")
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{CreatedAt: t.UTC(), ID: uid, Key: key}, nil
+}
+
+// Page is a parsed `limit` / `cursor` pair from a request's query string.
+type Page struct {
+	Limit int
+	After *Cursor
+}
+
+func Parse(query url.Values) (Page, error) {
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Limit: limit}
+
+	if c := query.Get("cursor"); c != "" {
+		cursor, err := Decode(c)
+		if err != nil {
+			return Page{}, err
+		}
+		page.After = &cursor
+	}
+
+	return page, nil
+}
+
+// FetchLimit is the number of rows to ask the database for: one more than the
+// page size so we can tell whether another page exists.
+func (p Page) FetchLimit() int32 {
+	return int32(p.Limit + 1)
+}
+
+func (p Page) AfterCreatedAt() sql.NullTime {
+	if p.After == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.After.CreatedAt, Valid: true}
+}
+
+func (p Page) AfterID() uuid.NullUUID {
+	if p.After == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.After.ID, Valid: true}
+}
+
+func (p Page) AfterKey() sql.NullString {
+	if p.After == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: p.After.Key, Valid: true}
+}
+
+// Trim cuts rows fetched with FetchLimit down to the page size and returns the
+// cursor for the next page, or "" when there are no more rows.
+func Trim[T any](p Page, rows []T, key func(T) Cursor) ([]T, string) {
+	if len(rows) <= p.Limit {
+		return rows, ""
+	}
+	rows = rows[:p.Limit]
+	return rows, key(rows[len(rows)-1]).Encode()
+}
+
+// OffsetPage is a `limit` / `cursor` pair for result sets that cannot be keyset
+// paginated, such as relevance-ranked search results. Its cursor wraps a plain
+// row offset but is just as opaque to clients.
+type OffsetPage struct {
+	Limit  int
+	Offset int
+}
+
+func ParseOffset(query url.Values) (OffsetPage, error) {
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		return OffsetPage{}, err
+	}
+	page := OffsetPage{Limit: limit}
+
+	if c := query.Get("cursor"); c != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(c)
+		if err != nil {
+			return OffsetPage{}, ErrInvalidCursor
+		}
+		// Offsets are sent to the database as int32s, so larger ones are
+		// rejected here rather than left to wrap around.
+		offset, err := strconv.ParseInt(strings.TrimPrefix(string(raw), "offset:"), 10, 32)
+		if err != nil || offset < 0 || !strings.HasPrefix(string(raw), "offset:") {
+			return OffsetPage{}, ErrInvalidCursor
+		}
+		page.Offset = int(offset)
+	}
+
+	return page, nil
+}
+
+func (p OffsetPage) FetchLimit() int32 {
+	return int32(p.Limit + 1)
+}
+
+// TrimOffset is Trim for offset pages.
+func TrimOffset[T any](p OffsetPage, rows []T) ([]T, string) {
+	if len(rows) <= p.Limit {
+		return rows, ""
+	}
+	next := "offset:" + strconv.Itoa(p.Offset+p.Limit)
+	return rows[:p.Limit], base64.RawURLEncoding.EncodeToString([]byte(next))
+}
+
+func parseLimit(l string) (int, error) {
+	if l == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, ErrInvalidLimit
+	}
+	return limit, nil
+}
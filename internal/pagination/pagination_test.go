@@ -0,0 +1,158 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 2, 14, 10, 30, 0, 123456000, time.UTC)
+
+	tests := []struct {
+		name string
+		want Cursor
+	}{
+		{name: "Without key", want: Cursor{CreatedAt: createdAt, ID: uuid.New()}},
+		{name: "With key", want: Cursor{CreatedAt: createdAt, ID: uuid.New(), Key: "bad word"}},
+		{name: "Key containing a comma", want: Cursor{CreatedAt: createdAt, ID: uuid.New(), Key: "a,b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.want.Encode())
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID || got.Key != tt.want.Key {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+
+	tests := []struct {
+		name      string
+		query     url.Values
+		wantLimit int
+		wantAfter bool
+		wantErr   bool
+	}{
+		{
+			name:      "Defaults",
+			query:     url.Values{},
+			wantLimit: DefaultLimit,
+		},
+		{
+			name:      "Limit and cursor",
+			query:     url.Values{"limit": {"5"}, "cursor": {cursor.Encode()}},
+			wantLimit: 5,
+			wantAfter: true,
+		},
+		{
+			name:    "Limit too large",
+			query:   url.Values{"limit": {"1000"}},
+			wantErr: true,
+		},
+		{
+			name:    "Limit not a number",
+			query:   url.Values{"limit": {"ten"}},
+			wantErr: true,
+		},
+		{
+			name:    "Garbage cursor",
+			query:   url.Values{"cursor": {"not-a-cursor"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := Parse(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if page.Limit != tt.wantLimit {
+				t.Errorf("Parse() limit = %d, want %d", page.Limit, tt.wantLimit)
+			}
+			if (page.After != nil) != tt.wantAfter {
+				t.Errorf("Parse() after = %v, wantAfter %v", page.After, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	page := Page{Limit: 2}
+	rows := []Cursor{
+		{CreatedAt: time.Now().UTC(), ID: uuid.New()},
+		{CreatedAt: time.Now().UTC(), ID: uuid.New()},
+		{CreatedAt: time.Now().UTC(), ID: uuid.New()},
+	}
+	key := func(c Cursor) Cursor { return c }
+
+	got, next := Trim(page, rows, key)
+	if len(got) != 2 {
+		t.Fatalf("Trim() returned %d rows, want 2", len(got))
+	}
+	if next != rows[1].Encode() {
+		t.Errorf("Trim() next = %q, want cursor of last returned row", next)
+	}
+
+	got, next = Trim(page, rows[:2], key)
+	if len(got) != 2 || next != "" {
+		t.Errorf("Trim() on last page = (%d rows, %q), want (2 rows, \"\")", len(got), next)
+	}
+}
+
+func TestOffsetRoundTrip(t *testing.T) {
+	page := OffsetPage{Limit: 2, Offset: 4}
+	rows, next := TrimOffset(page, []int{1, 2, 3})
+	if len(rows) != 2 || next == "" {
+		t.Fatalf("TrimOffset() = (%v, %q), want 2 rows and a cursor", rows, next)
+	}
+
+	got, err := ParseOffset(url.Values{"limit": {"2"}, "cursor": {next}})
+	if err != nil {
+		t.Fatalf("ParseOffset() error = %v", err)
+	}
+	if got.Offset != 6 {
+		t.Errorf("ParseOffset() offset = %d, want 6", got.Offset)
+	}
+
+	if _, err := ParseOffset(url.Values{"cursor": {Cursor{}.Encode()}}); err == nil {
+		t.Errorf("ParseOffset() accepted a keyset cursor")
+	}
+}
+
+func TestParseOffsetRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  string
+		wantErr bool
+	}{
+		{name: "Zero", offset: "0"},
+		{name: "Largest", offset: "2147483647"},
+		{name: "Too large", offset: "2147483648", wantErr: true},
+		{name: "Negative", offset: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := base64.RawURLEncoding.EncodeToString([]byte("offset:" + tt.offset))
+			_, err := ParseOffset(url.Values{"cursor": {cursor}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseOffset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("empty search query")
+
+// Snippet highlight markers passed to ts_headline.
+const (
+	StartSel = "<mark>"
+	StopSel  = "</mark>"
+)
+
+// ToTSQuery turns user input into a to_tsquery expression. It understands:
+//
+//	word      must match (terms are ANDed together)
+//	"a b c"   phrase, the words must appear next to each other
+//	pre*      prefix match
+//	-word     must not match
+//	a OR b    either term
+//
+// Everything other than letters and digits is dropped, so user input can never
+// inject tsquery operators of its own.
+func ToTSQuery(q string) (string, error) {
+	var clauses []string
+	joinWithOr := false
+
+	for _, tok := range tokenize(q) {
+		if tok == "OR" {
+			joinWithOr = len(clauses) > 0
+			continue
+		}
+
+		negate := false
+		if strings.HasPrefix(tok, "-") {
+			negate = true
+			tok = tok[1:]
+		}
+
+		var clause string
+		if strings.HasPrefix(tok, `"`) {
+			clause = phrase(strings.Trim(tok, `"`))
+		} else {
+			prefix := strings.HasSuffix(tok, "*")
+			clause = phrase(strings.TrimSuffix(tok, "*"))
+			if clause != "" && prefix {
+				clause += ":*"
+			}
+		}
+		if clause == "" {
+			continue
+		}
+		if negate {
+			clause = "!(" + clause + ")"
+		}
+
+		if joinWithOr {
+			clauses[len(clauses)-1] = clauses[len(clauses)-1] + " | " + clause
+			joinWithOr = false
+			continue
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return "", ErrEmptyQuery
+	}
+	for i, c := range clauses {
+		if strings.Contains(c, " | ") {
+			clauses[i] = "(" + c + ")"
+		}
+	}
+	return strings.Join(clauses, " & "), nil
+}
+
+// tokenize splits on whitespace while keeping double-quoted phrases together.
+func tokenize(q string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			cur.WriteRune(r)
+			if inQuote {
+				flush()
+			}
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// phrase keeps only the words in s and joins them with the followed-by
+// operator.
+func phrase(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " <-> ")
+}
+
+// EscapeSnippet HTML-escapes a ts_headline snippet while keeping the
+// highlight markers, so it is safe to render as HTML.
+func EscapeSnippet(snippet string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(snippet, StartSel)
+		if start < 0 {
+			break
+		}
+		stop := strings.Index(snippet[start:], StopSel)
+		if stop < 0 {
+			break
+		}
+		stop += start
+
+		b.WriteString(html.EscapeString(snippet[:start]))
+		b.WriteString(StartSel)
+		b.WriteString(html.EscapeString(snippet[start+len(StartSel) : stop]))
+		b.WriteString(StopSel)
+		snippet = snippet[stop+len(StopSel):]
+	}
+	b.WriteString(html.EscapeString(snippet))
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package search
+
+import "testing"
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Single word",
+			query: "gopher",
+			want:  "gopher",
+		},
+		{
+			name:  "Words are ANDed",
+			query: "go  gopher",
+			want:  "go & gopher",
+		},
+		{
+			name:  "Phrase",
+			query: `"hello big world" now`,
+			want:  "hello <-> big <-> world & now",
+		},
+		{
+			name:  "Prefix",
+			query: "chir*",
+			want:  "chir:*",
+		},
+		{
+			name:  "Negation",
+			query: "go -java",
+			want:  "go & !(java)",
+		},
+		{
+			name:  "OR",
+			query: "cats OR dogs pets",
+			want:  "(cats | dogs) & pets",
+		},
+		{
+			name:  "Operators are stripped",
+			query: "a&b | !c:*",
+			want:  "a <-> b & c:*",
+		},
+		{
+			name:    "Only punctuation",
+			query:   `!!! ""`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToTSQuery(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToTSQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ToTSQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeSnippet(t *testing.T) {
+	got := EscapeSnippet(`<b>bold</b> <mark>gopher</mark> & friends`)
+	want := `&lt;b&gt;bold&lt;/b&gt; <mark>gopher</mark> &amp; friends`
+	if got != want {
+		t.Errorf("EscapeSnippet() = %q, want %q", got, want)
+	}
+}
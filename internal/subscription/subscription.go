@@ -0,0 +1,124 @@
+// Package subscription works out how a Chirpy Red subscription changes in
+// response to billing events from Polka.
+package subscription
+
+import "time"
+
+// Subscription statuses. Past due and cancelled subscriptions keep Chirpy
+// Red until the period they paid for ends; expired ones have lost it.
+const (
+	Active    = "active"
+	PastDue   = "past_due"
+	Cancelled = "cancelled"
+	Expired   = "expired"
+)
+
+// Events Polka sends.
+const (
+	EventUpgraded   = "user.upgraded"
+	EventRenewed    = "subscription.renewed"
+	EventFailed     = "payment.failed"
+	EventCancelled  = "subscription.cancelled"
+	EventDowngraded = "user.downgraded"
+)
+
+// DefaultPlan is the plan an upgrade is for when Polka doesn't say.
+const DefaultPlan = "chirpy_red"
+
+// State is a user's subscription. The zero State means they have none.
+type State struct {
+	Plan        string
+	Status      string
+	PeriodStart time.Time
+	// PeriodEnd is zero for subscriptions with no known end.
+	PeriodEnd time.Time
+	// LastEventAt is when the most recent event applied to it happened.
+	LastEventAt time.Time
+}
+
+// Exists reports whether s is an actual subscription.
+func (s State) Exists() bool {
+	return s.Status != ""
+}
+
+// Red reports whether s entitles its user to Chirpy Red at now.
+func (s State) Red(now time.Time) bool {
+	if !s.Exists() || s.Status == Expired {
+		return false
+	}
+	return s.PeriodEnd.IsZero() || s.PeriodEnd.After(now)
+}
+
+// Event is a billing event. At is when it happened, which is not
+// necessarily when it arrives: Polka may deliver events out of order, and
+// redeliver old ones.
+type Event struct {
+	Type        string
+	At          time.Time
+	Plan        string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// Apply returns the subscription that results from e happening to s, at
+// now. ok is false if e changes nothing: it is of an unknown type, or older
+// than the last event applied to s, which must not be undone by it.
+func Apply(s State, e Event, now time.Time) (next State, ok bool) {
+	if s.Exists() && e.At.Before(s.LastEventAt) {
+		return s, false
+	}
+
+	switch e.Type {
+	case EventUpgraded, EventRenewed:
+		start := e.PeriodStart
+		if start.IsZero() {
+			start = now
+			// A renewal continues from where the current period ends.
+			if e.Type == EventRenewed && s.Exists() && s.PeriodEnd.After(now) {
+				start = s.PeriodEnd
+			}
+		}
+		end := e.PeriodEnd
+		if end.IsZero() {
+			end = start.AddDate(0, 1, 0)
+		}
+		plan := e.Plan
+		if plan == "" {
+			plan = DefaultPlan
+		}
+		return State{
+			Plan:        plan,
+			Status:      Active,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			LastEventAt: e.At,
+		}, true
+
+	case EventFailed, EventCancelled:
+		// There is nothing to mark if the user never subscribed.
+		if !s.Exists() {
+			return s, true
+		}
+		s.Status = PastDue
+		if e.Type == EventCancelled {
+			s.Status = Cancelled
+		}
+		// Subscriptions from before periods were tracked have no end, and
+		// would otherwise keep Chirpy Red forever.
+		if s.PeriodEnd.IsZero() {
+			s.PeriodEnd = e.At
+		}
+		s.LastEventAt = e.At
+		return s, true
+
+	case EventDowngraded:
+		if !s.Exists() {
+			return s, true
+		}
+		s.Status = Expired
+		s.PeriodEnd = now
+		s.LastEventAt = e.At
+		return s, true
+	}
+	return s, false
+}
@@ -0,0 +1,193 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	periodStart := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	lastEvent := now.Add(-time.Hour)
+
+	active := State{
+		Plan:        DefaultPlan,
+		Status:      Active,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		LastEventAt: lastEvent,
+	}
+	with := func(s State, f func(*State)) State {
+		f(&s)
+		return s
+	}
+
+	tests := []struct {
+		name   string
+		state  State
+		event  Event
+		want   State
+		wantOK bool
+	}{
+		{
+			name:  "Upgrade with no subscription",
+			event: Event{Type: EventUpgraded, At: now},
+			want: State{
+				Plan:        DefaultPlan,
+				Status:      Active,
+				PeriodStart: now,
+				PeriodEnd:   now.AddDate(0, 1, 0),
+				LastEventAt: now,
+			},
+			wantOK: true,
+		},
+		{
+			name:  "Upgrade with a period",
+			event: Event{Type: EventUpgraded, At: now, Plan: "pro", PeriodStart: periodStart, PeriodEnd: periodEnd},
+			want: State{
+				Plan:        "pro",
+				Status:      Active,
+				PeriodStart: periodStart,
+				PeriodEnd:   periodEnd,
+				LastEventAt: now,
+			},
+			wantOK: true,
+		},
+		{
+			name:  "Renewal continues from the current period",
+			state: active,
+			event: Event{Type: EventRenewed, At: now},
+			want: State{
+				Plan:        DefaultPlan,
+				Status:      Active,
+				PeriodStart: periodEnd,
+				PeriodEnd:   periodEnd.AddDate(0, 1, 0),
+				LastEventAt: now,
+			},
+			wantOK: true,
+		},
+		{
+			name:  "Renewal of a lapsed subscription starts now",
+			state: with(active, func(s *State) { s.Status = Expired; s.PeriodEnd = lastEvent }),
+			event: Event{Type: EventRenewed, At: now},
+			want: State{
+				Plan:        DefaultPlan,
+				Status:      Active,
+				PeriodStart: now,
+				PeriodEnd:   now.AddDate(0, 1, 0),
+				LastEventAt: now,
+			},
+			wantOK: true,
+		},
+		{
+			name:   "Payment failed",
+			state:  active,
+			event:  Event{Type: EventFailed, At: now},
+			want:   with(active, func(s *State) { s.Status = PastDue; s.LastEventAt = now }),
+			wantOK: true,
+		},
+		{
+			name:   "Cancelled",
+			state:  active,
+			event:  Event{Type: EventCancelled, At: now},
+			want:   with(active, func(s *State) { s.Status = Cancelled; s.LastEventAt = now }),
+			wantOK: true,
+		},
+		{
+			name:  "Cancelled with no known period end",
+			state: with(active, func(s *State) { s.PeriodEnd = time.Time{} }),
+			event: Event{Type: EventCancelled, At: now},
+			want: with(active, func(s *State) {
+				s.Status = Cancelled
+				s.PeriodEnd = now
+				s.LastEventAt = now
+			}),
+			wantOK: true,
+		},
+		{
+			name:  "Payment failed with no known period end",
+			state: with(active, func(s *State) { s.PeriodEnd = time.Time{} }),
+			event: Event{Type: EventFailed, At: now},
+			want: with(active, func(s *State) {
+				s.Status = PastDue
+				s.PeriodEnd = now
+				s.LastEventAt = now
+			}),
+			wantOK: true,
+		},
+		{
+			name:   "Cancelled with no subscription",
+			event:  Event{Type: EventCancelled, At: now},
+			wantOK: true,
+		},
+		{
+			name:   "Downgraded",
+			state:  active,
+			event:  Event{Type: EventDowngraded, At: now},
+			want:   with(active, func(s *State) { s.Status = Expired; s.PeriodEnd = now; s.LastEventAt = now }),
+			wantOK: true,
+		},
+		{
+			name:   "Downgraded with no subscription",
+			event:  Event{Type: EventDowngraded, At: now},
+			wantOK: true,
+		},
+		{
+			name:  "Old upgrade redelivered after a cancellation",
+			state: with(active, func(s *State) { s.Status = Cancelled }),
+			event: Event{Type: EventUpgraded, At: lastEvent.Add(-time.Minute)},
+			want:  with(active, func(s *State) { s.Status = Cancelled }),
+		},
+		{
+			name:   "Event at the same time as the last one",
+			state:  active,
+			event:  Event{Type: EventCancelled, At: lastEvent},
+			want:   with(active, func(s *State) { s.Status = Cancelled }),
+			wantOK: true,
+		},
+		{
+			name:  "Unknown event",
+			state: active,
+			event: Event{Type: "user.renamed", At: now},
+			want:  active,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Apply(tt.state, tt.event, now)
+			if ok != tt.wantOK {
+				t.Errorf("Apply() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("Apply() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRed(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		state State
+		want  bool
+	}{
+		{name: "No subscription", state: State{}},
+		{name: "Active", state: State{Status: Active, PeriodEnd: now.Add(time.Hour)}, want: true},
+		{name: "No known end", state: State{Status: Active}, want: true},
+		{name: "Cancelled within the period", state: State{Status: Cancelled, PeriodEnd: now.Add(time.Hour)}, want: true},
+		{name: "Past due after the period", state: State{Status: PastDue, PeriodEnd: now.Add(-time.Hour)}},
+		{name: "Expired", state: State{Status: Expired, PeriodEnd: now.Add(time.Hour)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.Red(now); got != tt.want {
+				t.Errorf("Red() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
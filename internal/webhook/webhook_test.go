@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:   "Valid signature",
+			header: Sign(body, now, "current"),
+			body:   body,
+		},
+		{
+			name:   "Signed with a previous secret",
+			header: Sign(body, now, "previous"),
+			body:   body,
+		},
+		{
+			name:   "One of several signatures matches",
+			header: Sign(body, now, "unknown", "current"),
+			body:   body,
+		},
+		{
+			name:    "Unknown secret",
+			header:  Sign(body, now, "unknown"),
+			body:    body,
+			wantErr: ErrSignatureInvalid,
+		},
+		{
+			name:    "Tampered body",
+			header:  Sign(body, now, "current"),
+			body:    []byte(`{"id":"evt_1","event":"user.downgraded"}`),
+			wantErr: ErrSignatureInvalid,
+		},
+		{
+			name:    "Replayed outside the tolerance",
+			header:  Sign(body, now.Add(-DefaultTolerance-time.Second), "current"),
+			body:    body,
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "Timestamp in the future",
+			header:  Sign(body, now.Add(DefaultTolerance+time.Second), "current"),
+			body:    body,
+			wantErr: ErrTimestamp,
+		},
+		{
+			name:    "No header",
+			body:    body,
+			wantErr: ErrNoSignature,
+		},
+		{
+			name:    "No signatures",
+			header:  "t=1700000000",
+			body:    body,
+			wantErr: ErrInvalidHeader,
+		},
+		{
+			name:    "Bad hex",
+			header:  "t=1700000000,v1=zz",
+			body:    body,
+			wantErr: ErrInvalidHeader,
+		},
+	}
+
+	v := NewVerifier([]string{"current", "previous"}, DefaultTolerance)
+	v.now = func() time.Time { return now }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(tt.header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSignIsDeterministic(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := Sign([]byte("body"), now, "secret")
+	b := Sign([]byte("body"), now, "secret")
+	if a != b {
+		t.Errorf("Sign() = %q then %q, want the same header", a, b)
+	}
+	if a[:13] != "t=1700000000," {
+		t.Errorf("Sign() = %q, want it to start with the timestamp", a)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Minute, MaxDelay: 10 * time.Minute, MaxAttempts: 6}
+
+	tests := []struct {
+		attempts  int
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{attempts: 1, wantDelay: time.Minute, wantRetry: true},
+		{attempts: 2, wantDelay: 2 * time.Minute, wantRetry: true},
+		{attempts: 4, wantDelay: 8 * time.Minute, wantRetry: true},
+		{attempts: 5, wantDelay: 10 * time.Minute, wantRetry: true},
+		{attempts: 6, wantRetry: false},
+		{attempts: 100, wantRetry: false},
+	}
+
+	for _, tt := range tests {
+		delay, retry := p.Next(tt.attempts)
+		if delay != tt.wantDelay || retry != tt.wantRetry {
+			t.Errorf("Next(%d) = %v, %v, want %v, %v", tt.attempts, delay, retry, tt.wantDelay, tt.wantRetry)
+		}
+	}
+}
+
+func TestDefaultRetryPolicyWindow(t *testing.T) {
+	var total time.Duration
+	for attempts := 1; attempts <= DefaultRetryPolicy.MaxAttempts; attempts++ {
+		delay, retry := DefaultRetryPolicy.Next(attempts)
+		if retry {
+			total += delay
+		}
+	}
+
+	want := 4*time.Hour + 15*time.Minute + 30*time.Second
+	if total != want {
+		t.Errorf("DefaultRetryPolicy retries for %v, want %v", total, want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"chirp.created"}`)
+
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "Accepted", status: 204, wantStatus: 204},
+		{name: "Server error", status: 500, wantStatus: 500, wantErr: true},
+		{name: "Redirect is not followed", status: 302, wantStatus: 302, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+				if tt.status == 302 {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			s := NewSender(time.Second, true)
+			status, err := s.Send(context.Background(), srv.URL, "secret", "chirp.created", "dlv_1", body)
+			if status != tt.wantStatus || (err != nil) != tt.wantErr {
+				t.Fatalf("Send() = %d, %v, want %d, error %v", status, err, tt.wantStatus, tt.wantErr)
+			}
+			if got.Header.Get(EventHeader) != "chirp.created" || got.Header.Get(DeliveryHeader) != "dlv_1" {
+				t.Errorf("Send() headers = %v, want event and delivery set", got.Header)
+			}
+			v := NewVerifier([]string{"secret"}, DefaultTolerance)
+			if err := v.Verify(got.Header.Get(SignatureHeader), gotBody); err != nil {
+				t.Errorf("Verify() of sent delivery error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	s := NewSender(time.Second, false)
+	_, err := s.Send(context.Background(), srv.URL, "secret", "chirp.created", "dlv_1", []byte("{}"))
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Send() error = %v, want %v", err, ErrPrivateAddress)
+	}
+	if called {
+		t.Error("Send() reached a loopback endpoint")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+// loadSigningKeys is the keyring's loader. Retired keys are returned until
+// every token they signed has expired.
+func (cfg *apiConfig) loadSigningKeys(ctx context.Context) ([]auth.SigningKey, error) {
+	rows, err := cfg.dbQueries.ListSigningKeys(ctx, sql.NullTime{
+		Time:  time.Now().Add(-auth.RetiredKeyTTL),
+		Valid: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]auth.SigningKey, 0, len(rows))
+	for _, row := range rows {
+		key, err := auth.ParseSigningKey(row.ID, row.Algorithm, row.PrivateKey, row.CreatedAt, row.RetiredAt.Valid)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (cfg *apiConfig) createSigningKey(ctx context.Context, q *database.Queries) (auth.SigningKey, error) {
+	key, err := auth.GenerateSigningKey(cfg.JWT_SIGNING_ALG)
+	if err != nil {
+		return auth.SigningKey{}, err
+	}
+
+	der, err := key.MarshalPrivateKey()
+	if err != nil {
+		return auth.SigningKey{}, err
+	}
+
+	err = q.CreateSigningKey(ctx, database.CreateSigningKeyParams{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: der,
+		CreatedAt:  key.CreatedAt,
+	})
+	return key, err
+}
+
+// ensureSigningKey loads the keyring and creates the first key on a fresh
+// database.
+func (cfg *apiConfig) ensureSigningKey(ctx context.Context) error {
+	if err := cfg.keys.Reload(ctx); err != nil {
+		return err
+	}
+	if cfg.keys.ActiveKeyID() != "" {
+		return nil
+	}
+
+	if _, err := cfg.createSigningKey(ctx, cfg.dbQueries); err != nil {
+		return err
+	}
+	return cfg.keys.Reload(ctx)
+}
+
+func (cfg *apiConfig) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helper.RespondWithJson(w, 200, cfg.keys.JWKS())
+}
+
+// handleRotateSigningKey makes a new key active. The previous one is retired
+// but keeps verifying, so nobody is logged out.
+func (cfg *apiConfig) handleRotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		KeyID     string    `json:"kid"`
+		Algorithm string    `json:"alg"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to rotate signing key", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	key, err := cfg.createSigningKey(r.Context(), qtx)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to rotate signing key", err)
+		return
+	}
+
+	if err := qtx.RetireSigningKeys(r.Context(), key.ID); err != nil {
+		helper.RespondWithError(w, 500, "unable to rotate signing key", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to rotate signing key", err)
+		return
+	}
+
+	if err := cfg.keys.Reload(r.Context()); err != nil {
+		helper.RespondWithError(w, 500, "unable to reload signing keys", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 201, response{
+		KeyID:     key.ID,
+		Algorithm: key.Algorithm,
+		CreatedAt: key.CreatedAt,
+	})
+}
+
+// authenticate resolves a bearer credential, which is either a JWT access
+// token or a personal API key, for the auth middleware.
+func (cfg *apiConfig) authenticate(ctx context.Context, token string) (auth.Principal, error) {
+	var p auth.Principal
+	if auth.IsAPIKey(token) {
+		key, err := cfg.dbQueries.UseAPIKey(ctx, auth.HashToken(token))
+		if err != nil {
+			return auth.Principal{}, err
+		}
+		p = auth.Principal{UserID: key.UserID, Scopes: key.Scopes, TokenID: key.ID.String(), APIKey: true}
+	} else {
+		var err error
+		if p, err = cfg.keys.ParseAccessToken(token); err != nil {
+			return auth.Principal{}, err
+		}
+	}
+
+	if err := cfg.checkAccess(ctx, p); err != nil {
+		return auth.Principal{}, err
+	}
+	return p, nil
+}
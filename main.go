@@ -1,17 +1,31 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/thetsajeet/chirpy/internal/auth"
 	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entitlements"
 	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/lockout"
+	"github.com/thetsajeet/chirpy/internal/mailer"
+	"github.com/thetsajeet/chirpy/internal/moderation"
+	"github.com/thetsajeet/chirpy/internal/webhook"
 )
 
 func main() {
@@ -25,45 +39,241 @@ func main() {
 	}
 
 	const filepathRoot = "."
-	const port = "8080"
+	port := getEnv("PORT", "8080")
 
+	// The timeouts stop slow or idle clients from holding connections open
+	// indefinitely.
 	mux := http.NewServeMux()
 	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: getEnvDuration("READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:       getEnvDuration("IDLE_TIMEOUT", 2*time.Minute),
+		MaxHeaderBytes:    getEnvInt("MAX_HEADER_BYTES", http.DefaultMaxHeaderBytes),
 	}
 
 	apiCfg := apiConfig{
-		fileServerHits: atomic.Int32{},
-		dbQueries:      database.New(db),
-		PLATFORM:       os.Getenv("PLATFORM"),
-		JWT_SECRET:     os.Getenv("JWT_SECRET"),
-		POLKA_KEY:      os.Getenv("POLKA_KEY"),
+		fileServerHits:       atomic.Int32{},
+		db:                   db,
+		dbQueries:            database.New(db),
+		PLATFORM:             os.Getenv("PLATFORM"),
+		JWT_SECRET:           os.Getenv("JWT_SECRET"),
+		JWT_SIGNING_ALG:      getEnv("JWT_SIGNING_ALG", auth.AlgRS256),
+		POLKA_KEY:            os.Getenv("POLKA_KEY"),
+		POLKA_ALLOW_UNSIGNED: os.Getenv("POLKA_ALLOW_UNSIGNED") == "true",
+		TRUST_PROXY:          os.Getenv("TRUST_PROXY") == "true",
+		TRUSTED_PROXY_HOPS:   getEnvInt("TRUSTED_PROXY_HOPS", 1),
+		APP_BASE_URL:         os.Getenv("APP_BASE_URL"),
+
+		CHIRP_EDIT_WINDOW:      getEnvDuration("CHIRP_EDIT_WINDOW", 15*time.Minute),
+		REFRESH_TOKEN_TTL:      getEnvDuration("REFRESH_TOKEN_TTL", 60*24*time.Hour),
+		PASSWORD_RESET_TTL:     getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		EMAIL_VERIFICATION_TTL: getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+	}
+
+	if apiCfg.JWT_SIGNING_ALG != auth.AlgRS256 && apiCfg.JWT_SIGNING_ALG != auth.AlgEdDSA {
+		log.Fatalf("JWT_SIGNING_ALG must be %s or %s", auth.AlgRS256, auth.AlgEdDSA)
+	}
+
+	// JWT_SECRET used to sign every token. It is now only needed to accept
+	// tokens issued before the switch to asymmetric keys, and only until
+	// JWT_SECRET_ACCEPT_UNTIL. Left unset, that is one token lifetime after
+	// startup, which is enough for the first deploy after the switch.
+	legacyUntil := getEnvTime("JWT_SECRET_ACCEPT_UNTIL", time.Now().Add(auth.LegacyTokenTTL))
+	apiCfg.keys = auth.NewKeyring(apiCfg.loadSigningKeys, apiCfg.JWT_SECRET, legacyUntil)
+	if err := apiCfg.ensureSigningKey(context.Background()); err != nil {
+		log.Fatalf("unable to load signing keys: %v", err)
+	}
+
+	apiCfg.auth = auth.NewMiddleware(apiCfg.authenticate)
+
+	if secrets := splitList(os.Getenv("POLKA_WEBHOOK_SECRETS")); len(secrets) > 0 {
+		apiCfg.polka = webhook.NewVerifier(secrets, getEnvDuration("POLKA_WEBHOOK_TOLERANCE", webhook.DefaultTolerance))
 	}
 
-	if apiCfg.JWT_SECRET == "" {
-		log.Fatal("JWT_SECRET is not set")
+	parallelism := getEnvInt("ARGON2_PARALLELISM", int(auth.DefaultArgon2Params.Parallelism))
+	if parallelism > math.MaxUint8 {
+		log.Fatalf("ARGON2_PARALLELISM: must be at most %d", math.MaxUint8)
+	}
+	apiCfg.passwords = auth.NewHasher(auth.Argon2Params{
+		Memory:      uint32(getEnvInt("ARGON2_MEMORY_KIB", int(auth.DefaultArgon2Params.Memory))),
+		Iterations:  uint32(getEnvInt("ARGON2_ITERATIONS", int(auth.DefaultArgon2Params.Iterations))),
+		Parallelism: uint8(parallelism),
+		SaltLength:  auth.DefaultArgon2Params.SaltLength,
+		KeyLength:   auth.DefaultArgon2Params.KeyLength,
+	})
+
+	apiCfg.mailer = newMailer()
+	apiCfg.webhooks = webhook.NewSender(webhookTimeout, os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true")
+
+	if emails := os.Getenv("ADMIN_EMAILS"); emails != "" {
+		if err := apiCfg.promoteAdmins(context.Background(), emails); err != nil {
+			log.Fatalf("unable to promote ADMIN_EMAILS: %v", err)
+		}
+	}
+
+	throttleStore := newThrottleStore(db, apiCfg.dbQueries)
+	apiCfg.accountThrottle = lockout.New(throttleStore, accountThrottlePolicy)
+	apiCfg.ipThrottle = lockout.New(throttleStore, ipThrottlePolicy)
+
+	apiCfg.moderationWords = moderation.NewWordList(nil)
+	apiCfg.chirpFilter = apiCfg.moderationWords
+	if path := os.Getenv("MODERATION_WORDLIST"); path != "" {
+		if err := apiCfg.seedModerationWords(context.Background(), path); err != nil {
+			log.Fatalf("unable to seed moderation words: %v", err)
+		}
+	}
+	if err := apiCfg.loadModerationWords(context.Background()); err != nil {
+		log.Fatalf("unable to load moderation words: %v", err)
+	}
+
+	apiCfg.entitlements = entitlements.Default()
+	if path := os.Getenv("ENTITLEMENTS_FILE"); path != "" {
+		plans, err := entitlements.LoadFile(path)
+		if err != nil {
+			log.Fatalf("unable to load entitlements: %v", err)
+		}
+		apiCfg.entitlements = plans
+	} else {
+		// Without a file, CHIRP_EDIT_WINDOW keeps setting the free plan's
+		// edit window as it did before plans existed.
+		free := apiCfg.entitlements.Plans[entitlements.FreePlan]
+		free.EditWindow = entitlements.Duration{Duration: apiCfg.CHIRP_EDIT_WINDOW}
+		apiCfg.entitlements.Plans[entitlements.FreePlan] = free
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){
+		func(ctx context.Context) { apiCfg.expireSubscriptions(ctx, subscriptionExpiryInterval) },
+		func(ctx context.Context) { apiCfg.publishScheduledChirps(ctx, scheduledChirpInterval) },
+		func(ctx context.Context) { apiCfg.dispatchWebhooks(ctx, webhookDispatchInterval) },
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
 	}
 
 	filepathHandler := http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot)))
 	mux.Handle("/app/", apiCfg.middlewareMetricsInfo(filepathHandler))
 	mux.HandleFunc("GET /api/healthz", handlerHealthz)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerFileServerHits)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerResetMetrics)
-	mux.HandleFunc("GET /api/chirps", apiCfg.AllChirps)
-	mux.HandleFunc("POST /api/chirps", apiCfg.CreateChirp)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.GetChirp)
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.handleJWKS)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handlerFileServerHits))
+	// The PLATFORM=dev gate stays on top of the admin check on purpose: the
+	// reset deletes every user, so no role, however it was obtained, should
+	// be able to run it against production data.
+	mux.HandleFunc("POST /admin/reset", apiCfg.requireRole(auth.RoleAdmin, apiCfg.middlewareDevOnly(apiCfg.handlerResetMetrics)))
+	mux.HandleFunc("GET /api/chirps", apiCfg.auth.Optional(apiCfg.AllChirps))
+	mux.HandleFunc("POST /api/chirps", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.CreateChirp))
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.auth.Optional(apiCfg.SearchChirps))
+	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.auth.Optional(apiCfg.GetChirp))
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.EditChirp))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.ChirpHistory)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.auth.Optional(apiCfg.ChirpThread))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.LikeChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.UnlikeChirp))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.RechirpChirp))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.UndoRechirp))
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.auth.Require(auth.ScopeChirpsRead, apiCfg.handleListScheduledChirps))
+	mux.HandleFunc("POST /api/scheduled-chirps", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.handleScheduleChirp))
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{scheduledID}", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.handleDeleteScheduledChirp))
 
 	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.handleLoginMFA)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handleRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handleRevoke)
-	mux.HandleFunc("PUT /api/users", apiCfg.handleUpdate)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.DeleteChirp)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.UpgradeUser)
+	mux.HandleFunc("PUT /api/users", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleUpdate))
+	mux.HandleFunc("GET /api/users/me/subscription", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleGetSubscription))
+	mux.HandleFunc("GET /api/users/me/entitlements", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleGetEntitlements))
+	mux.HandleFunc("GET /api/users/{userID}", apiCfg.handleGetProfile)
+	mux.HandleFunc("POST /api/users/verify", apiCfg.handleVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify/resend", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleResendVerification))
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.handleForgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.handleResetPassword)
+	mux.HandleFunc("GET /api/sessions", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleListSessions))
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleRevokeSession))
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleRevokeAllSessions))
+	mux.HandleFunc("GET /api/keys", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleListAPIKeys))
+	mux.HandleFunc("POST /api/keys", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleCreateAPIKey))
+	mux.HandleFunc("DELETE /api/keys/{keyID}", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleRevokeAPIKey))
+	mux.HandleFunc("POST /api/mfa/totp/enroll", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleEnrollTOTP))
+	mux.HandleFunc("POST /api/mfa/totp/verify", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleVerifyTOTP))
+	mux.HandleFunc("POST /api/mfa/totp/disable", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleDisableTOTP))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.auth.Require(auth.ScopeChirpsWrite, apiCfg.DeleteChirp))
+	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlePolkaWebhook)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleListWebhookEndpoints))
+	mux.HandleFunc("POST /api/webhooks", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleCreateWebhookEndpoint))
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleDeleteWebhookEndpoint))
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", apiCfg.auth.Require(auth.ScopeAccountRead, apiCfg.handleListWebhookDeliveries))
+	mux.HandleFunc("POST /api/webhooks/{endpointID}/deliveries/{deliveryID}/replay", apiCfg.auth.Require(auth.ScopeAccountWrite, apiCfg.handleReplayWebhookDelivery))
+
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.auth.Require(auth.ScopeFollowsWrite, apiCfg.handleFollow))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.auth.Require(auth.ScopeFollowsWrite, apiCfg.handleUnfollow))
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handleFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handleFollowing)
+	mux.HandleFunc("GET /api/timeline", apiCfg.auth.Require(auth.ScopeChirpsRead, apiCfg.handleTimeline))
+
+	mux.HandleFunc("GET /admin/moderation/words", apiCfg.requireRole(auth.RoleModerator, apiCfg.handleListModerationWords))
+	mux.HandleFunc("PUT /admin/moderation/words/{word}", apiCfg.requireRole(auth.RoleModerator, apiCfg.handlePutModerationWord))
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", apiCfg.requireRole(auth.RoleModerator, apiCfg.handleDeleteModerationWord))
+	mux.HandleFunc("GET /admin/moderation/flags", apiCfg.requireRole(auth.RoleModerator, apiCfg.handleListFlags))
+	mux.HandleFunc("DELETE /admin/moderation/flags/{chirpID}", apiCfg.requireRole(auth.RoleModerator, apiCfg.handleResolveFlags))
+	mux.HandleFunc("DELETE /admin/chirps/{chirpID}", apiCfg.requireRole(auth.RoleModerator, apiCfg.handleAdminDeleteChirp))
+	mux.HandleFunc("POST /admin/keys/rotate", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleRotateSigningKey))
+
+	mux.HandleFunc("GET /admin/users", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleListUsers))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleSetUserRole))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleSuspendUser))
+	mux.HandleFunc("POST /admin/users/{userID}/unsuspend", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleUnsuspendUser))
+	mux.HandleFunc("POST /admin/users/{userID}/logout", apiCfg.requireRole(auth.RoleAdmin, apiCfg.handleForceLogout))
+
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.auth.Optional(apiCfg.HashtagChirps))
+	mux.HandleFunc("GET /api/users/{userID}/mentions", apiCfg.auth.Optional(apiCfg.handleMentions))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// ListenAndServe only returns by itself when the server can't run.
+	failed := false
+	select {
+	case err := <-serverErr:
+		log.Printf("server stopped: %v", err)
+		failed = true
+	case <-ctx.Done():
+		log.Println("shutting down")
+	}
+	// A second signal kills the process instead of waiting for the drain.
+	stop()
+
+	// In-flight requests get until SHUTDOWN_TIMEOUT to finish, then the
+	// background workers are stopped before the database they use is
+	// closed.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second))
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("unable to drain connections: %v", err)
+	}
+
+	stopWorkers()
+	workers.Wait()
 
-	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
-	log.Fatal(server.ListenAndServe())
+	if err := db.Close(); err != nil {
+		log.Printf("unable to close database: %v", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
 
 func (cfg *apiConfig) middlewareMetricsInfo(next http.Handler) http.Handler {
@@ -74,6 +284,16 @@ func (cfg *apiConfig) middlewareMetricsInfo(next http.Handler) http.Handler {
 	})
 }
 
+func (cfg *apiConfig) middlewareDevOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.PLATFORM != "dev" {
+			helper.RespondWithError(w, 403, "forbidden", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
 func (cfg *apiConfig) handlerFileServerHits(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	htmlTemplate := `
@@ -89,11 +309,12 @@ func (cfg *apiConfig) handlerFileServerHits(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// handlerResetMetrics wipes every user along with the hit counter. Only
+// admins can call it, and only with PLATFORM=dev, since it is a fixture for
+// local development rather than something to run against real users. It
+// wipes the admins too; ADMIN_EMAILS promotes them again when the server
+// next starts after they sign up.
 func (cfg *apiConfig) handlerResetMetrics(w http.ResponseWriter, r *http.Request) {
-	if cfg.PLATFORM != "dev" {
-		w.WriteHeader(403)
-	}
-
 	cfg.fileServerHits.Store(0)
 	err := cfg.dbQueries.DeleteAllUsers(r.Context())
 	if err != nil {
@@ -109,3 +330,94 @@ func handlerHealthz(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// newMailer picks the mailer from MAILER. "log" (the default) writes mail to
+// MAIL_LOG_FILE, or stdout, instead of sending it.
+func newMailer() mailer.Mailer {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return mailer.NewSMTP(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("MAIL_FROM"),
+		)
+	case "", "log":
+		path := os.Getenv("MAIL_LOG_FILE")
+		if path == "" {
+			return mailer.NewLog(os.Stdout)
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("MAIL_LOG_FILE: %v", err)
+		}
+		return mailer.NewLog(f)
+	default:
+		log.Fatalf("MAILER must be smtp or log, got %q", os.Getenv("MAILER"))
+		return nil
+	}
+}
+
+// getEnv reads a string from the environment, falling back to def when the
+// variable is unset.
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// splitList splits a comma-separated environment value, dropping empty
+// entries.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvInt reads a positive integer that fits in both a uint32 and an int
+// from the environment, falling back to def when the variable is unset.
+func getEnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || n == 0 || n > math.MaxInt {
+		log.Fatalf("%s: must be a positive integer", key)
+	}
+	return int(n)
+}
+
+// getEnvDuration reads a time.Duration such as "15m" from the environment,
+// falling back to def when the variable is unset.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return d
+}
+
+// getEnvTime reads an RFC 3339 time such as "2025-06-01T00:00:00Z" from the
+// environment, falling back to def when the variable is unset.
+func getEnvTime(key string, def time.Time) time.Time {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return t
+}
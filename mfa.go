@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+const recoveryCodeCount = 10
+
+// handleEnrollTOTP starts two-factor enrollment by generating a new secret.
+// It is not enforced at login until the user proves their authenticator
+// works through handleVerifyTOTP.
+func (cfg *apiConfig) handleEnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		helper.RespondWithError(w, 409, "two-factor authentication is already enabled", nil)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to generate secret", err)
+		return
+	}
+
+	if err := cfg.dbQueries.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	}); err != nil {
+		helper.RespondWithError(w, 500, "unable to start enrollment", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, response{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(secret, user.Email),
+	})
+}
+
+// handleVerifyTOTP finishes enrollment with a code from the authenticator
+// and returns the recovery codes. They are only ever shown here.
+func (cfg *apiConfig) handleVerifyTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		helper.RespondWithError(w, 409, "two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		helper.RespondWithError(w, 400, "two-factor enrollment has not been started", nil)
+		return
+	}
+
+	ok, err := cfg.checkTOTP(r.Context(), cfg.dbQueries, user, params.Code)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify code", err)
+		return
+	}
+	if !ok {
+		helper.RespondWithError(w, 401, "invalid code", nil)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to generate recovery codes", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to enable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if err := qtx.EnableTOTP(r.Context(), user.ID); err != nil {
+		helper.RespondWithError(w, 500, "unable to enable two-factor authentication", err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		helper.RespondWithError(w, 500, "unable to store recovery codes", err)
+		return
+	}
+	for _, code := range codes {
+		if err := qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(code),
+		}); err != nil {
+			helper.RespondWithError(w, 500, "unable to store recovery codes", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to enable two-factor authentication", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, response{RecoveryCodes: codes})
+}
+
+// handleDisableTOTP turns two-factor authentication off. It asks for a
+// current code or a recovery code so a stolen access token alone can't do it.
+func (cfg *apiConfig) handleDisableTOTP(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		helper.RespondWithError(w, 409, "two-factor authentication is not enabled", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to disable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	ok, err := cfg.checkSecondFactor(r.Context(), qtx, user, params.Code, params.RecoveryCode)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify code", err)
+		return
+	}
+	if !ok {
+		helper.RespondWithError(w, 401, "invalid code", nil)
+		return
+	}
+
+	if err := qtx.DisableTOTP(r.Context(), user.ID); err != nil {
+		helper.RespondWithError(w, 500, "unable to disable two-factor authentication", err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), user.ID); err != nil {
+		helper.RespondWithError(w, 500, "unable to disable two-factor authentication", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to disable two-factor authentication", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// handleLoginMFA is the second step of login for accounts with two-factor
+// authentication: it exchanges the challenge token from handlerLogin plus a
+// TOTP or recovery code for the usual access and refresh tokens.
+func (cfg *apiConfig) handleLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	userID, err := cfg.keys.ValidateMFAToken(params.MFAToken)
+	if err != nil {
+		helper.RespondWithError(w, 401, "Unauthorized", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "Unauthorized", err)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		helper.RespondWithError(w, 401, "Unauthorized", errors.New("two-factor authentication is not enabled"))
+		return
+	}
+	if user.SuspendedAt.Valid {
+		helper.RespondWithError(w, 403, "account suspended", auth.ErrAccountSuspended)
+		return
+	}
+
+	// Whoever holds a challenge token already knows the password, so the
+	// codes get their own counter rather than sharing the password's.
+	mfaKey := "mfa:" + user.ID.String()
+	attempt, reserved := reserveLogin(w, r, cfg.loginThrottleKeys(r, mfaKey))
+	if !reserved {
+		return
+	}
+	defer attempt.release(r.Context())
+
+	ok, err := cfg.checkSecondFactor(r.Context(), cfg.dbQueries, user, params.Code, params.RecoveryCode)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify code", err)
+		return
+	}
+	if !ok {
+		cfg.failLogin(w, r, attempt, uuid.NullUUID{UUID: user.ID, Valid: true})
+		helper.RespondWithError(w, 401, "invalid code", nil)
+		return
+	}
+	attempt.succeed(r.Context())
+
+	cfg.respondWithLogin(w, r, user)
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code,
+// which is then burned.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, q *database.Queries, user database.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		n, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		return n == 1, err
+	}
+	return cfg.checkTOTP(ctx, q, user, code)
+}
+
+// checkTOTP validates a code against the user's secret and records its time
+// step, so each code is accepted at most once.
+func (cfg *apiConfig) checkTOTP(ctx context.Context, q *database.Queries, user database.User, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	n, err := q.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		ID:           user.ID,
+		TotpLastStep: step,
+	})
+	return n == 1, err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/moderation"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+)
+
+type ChirpFlag struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// seedModerationWords adds the words from a word-list file to the database
+// without touching words an admin has already configured.
+func (cfg *apiConfig) seedModerationWords(ctx context.Context, path string) error {
+	rules, err := moderation.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if err := cfg.dbQueries.SeedModerationWord(ctx, database.SeedModerationWordParams{
+			Word:   moderation.Normalize(rule.Word),
+			Action: string(rule.Action),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadModerationWords refreshes the in-memory word list from the database.
+func (cfg *apiConfig) loadModerationWords(ctx context.Context) error {
+	words, err := cfg.dbQueries.ListModerationWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]moderation.Rule, 0, len(words))
+	for _, w := range words {
+		rules = append(rules, moderation.Rule{Word: w.Word, Action: moderation.Action(w.Action)})
+	}
+	cfg.moderationWords.Replace(rules)
+	return nil
+}
+
+func (cfg *apiConfig) handleListModerationWords(w http.ResponseWriter, r *http.Request) {
+	helper.RespondWithJson(w, 200, cfg.moderationWords.Rules())
+}
+
+func (cfg *apiConfig) handlePutModerationWord(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action moderation.Action `json:"action"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode json", err)
+		return
+	}
+
+	if !params.Action.Valid() {
+		helper.RespondWithError(w, 400, "action must be one of mask, reject, flag", nil)
+		return
+	}
+
+	word := moderation.Normalize(r.PathValue("word"))
+	if word == "" {
+		helper.RespondWithError(w, 400, "invalid word", nil)
+		return
+	}
+
+	if err := cfg.dbQueries.UpsertModerationWord(r.Context(), database.UpsertModerationWordParams{
+		Word:   word,
+		Action: string(params.Action),
+	}); err != nil {
+		helper.RespondWithError(w, 500, "unable to save word", err)
+		return
+	}
+
+	if err := cfg.loadModerationWords(r.Context()); err != nil {
+		helper.RespondWithError(w, 500, "unable to reload word list", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, moderation.Rule{Word: word, Action: params.Action})
+}
+
+func (cfg *apiConfig) handleDeleteModerationWord(w http.ResponseWriter, r *http.Request) {
+	if err := cfg.dbQueries.DeleteModerationWord(r.Context(), moderation.Normalize(r.PathValue("word"))); err != nil {
+		helper.RespondWithError(w, 500, "unable to delete word", err)
+		return
+	}
+
+	if err := cfg.loadModerationWords(r.Context()); err != nil {
+		helper.RespondWithError(w, 500, "unable to reload word list", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+func (cfg *apiConfig) handleListFlags(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Flags      []ChirpFlag `json:"flags"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListPendingFlags(r.Context(), database.ListPendingFlagsParams{
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		AfterWord:      page.AfterKey(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list flags", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(f database.ListPendingFlagsRow) pagination.Cursor {
+		// A chirp can be flagged for several words at once.
+		return pagination.Cursor{CreatedAt: f.CreatedAt, ID: f.ChirpID, Key: f.Word}
+	})
+
+	resp := response{Flags: make([]ChirpFlag, 0, len(rows)), NextCursor: nextCursor}
+	for _, f := range rows {
+		resp.Flags = append(resp.Flags, ChirpFlag{
+			ChirpID:   f.ChirpID,
+			UserID:    f.UserID,
+			Body:      f.Body,
+			Word:      f.Word,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func (cfg *apiConfig) handleResolveFlags(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid chirp id", err)
+		return
+	}
+
+	if err := cfg.dbQueries.ResolveChirpFlags(r.Context(), chirpID); err != nil {
+		helper.RespondWithError(w, 500, "unable to resolve flags", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+	"github.com/thetsajeet/chirpy/internal/webhook"
+)
+
+// Events outbound webhooks can subscribe to.
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+	eventUserFollowed = "user.followed"
+)
+
+var webhookEvents = []string{eventChirpCreated, eventChirpDeleted, eventUserFollowed}
+
+// Delivery statuses. A delivery is dead once it has used up its retries; it
+// stays dead until its owner replays it.
+const (
+	deliveryPending   = "pending"
+	deliverySucceeded = "succeeded"
+	deliveryDead      = "dead"
+)
+
+const (
+	// webhookDispatchInterval is how often the queue is checked for due
+	// deliveries.
+	webhookDispatchInterval = 5 * time.Second
+	// webhookBatchSize is how many deliveries are sent at once.
+	webhookBatchSize = 20
+	// webhookTimeout is how long an endpoint has to respond.
+	webhookTimeout = 10 * time.Second
+	// webhookLease is how long a claimed delivery is hidden from other
+	// dispatchers. It has to outlast webhookTimeout.
+	webhookLease = time.Minute
+	// maxWebhookEndpoints is how many endpoints a user can register.
+	maxWebhookEndpoints = 10
+)
+
+// outboundEvent is the body of every outbound delivery. ID is the same on
+// every delivery of the event, including retries and replays, so receivers
+// can drop duplicates.
+type outboundEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// enqueueWebhookEvent queues eventType for every endpoint subscribed to it
+// that belongs to one of userIDs or listens to all users. Pass the
+// transaction that makes the change so the event is only sent if it
+// commits.
+func enqueueWebhookEvent(ctx context.Context, q *database.Queries, eventType string, data any, userIDs ...uuid.UUID) error {
+	eventID := uuid.New()
+	now := time.Now().UTC()
+	payload, err := json.Marshal(outboundEvent{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		NextAttemptAt: now,
+		UserIds:       userIDs,
+	})
+	return err
+}
+
+// WebhookEndpoint is an outbound webhook as shown to its owner. Secret is
+// only set in the response that creates it.
+type WebhookEndpoint struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	AllUsers  bool      `json:"all_users"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func newWebhookEndpoint(e database.WebhookEndpoint) WebhookEndpoint {
+	return WebhookEndpoint{
+		ID:        e.ID,
+		URL:       e.Url,
+		Events:    e.Events,
+		AllUsers:  e.AllUsers,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	LastStatusCode *int32          `json:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+func newWebhookDelivery(d database.WebhookDelivery) WebhookDelivery {
+	delivery := WebhookDelivery{
+		ID:        d.ID,
+		EventID:   d.EventID,
+		Event:     d.EventType,
+		Payload:   json.RawMessage(d.Payload),
+		Status:    d.Status,
+		Attempts:  d.Attempts,
+		LastError: d.LastError.String,
+		CreatedAt: d.CreatedAt,
+	}
+	if d.Status == deliveryPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		delivery.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.LastStatusCode.Valid {
+		delivery.LastStatusCode = &d.LastStatusCode.Int32
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return delivery
+}
+
+type webhookDeliveryPage struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handleCreateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		URL      string   `json:"url"`
+		Events   []string `json:"events"`
+		AllUsers bool     `json:"all_users"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	if err := cfg.checkWebhookURL(params.URL); err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	events := []string{}
+	for _, e := range params.Events {
+		if !slices.Contains(webhookEvents, e) {
+			helper.RespondWithError(w, 400, "unknown event "+e, nil)
+			return
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		helper.RespondWithError(w, 400, "at least one event is required", nil)
+		return
+	}
+
+	// Events about every user are only for admins.
+	if params.AllUsers {
+		access, err := cfg.dbQueries.GetUserAccess(r.Context(), userID)
+		if err != nil {
+			helper.RespondWithError(w, 500, "unable to create webhook", err)
+			return
+		}
+		if !auth.Authorize(auth.PrincipalFrom(r.Context()), access.Role, auth.RoleAdmin) {
+			helper.RespondWithError(w, 403, "only admins can subscribe to all users", nil)
+			return
+		}
+	}
+
+	count, err := cfg.dbQueries.CountWebhookEndpoints(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create webhook", err)
+		return
+	}
+	if count >= maxWebhookEndpoints {
+		helper.RespondWithError(w, 403, "too many webhooks", nil)
+		return
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create webhook", err)
+		return
+	}
+	secret := "whsec_" + token
+
+	endpoint, err := cfg.dbQueries.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID:   userID,
+		Url:      params.URL,
+		Secret:   secret,
+		Events:   events,
+		AllUsers: params.AllUsers,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create webhook", err)
+		return
+	}
+
+	resp := newWebhookEndpoint(endpoint)
+	resp.Secret = secret
+	helper.RespondWithJson(w, 201, resp)
+}
+
+// checkWebhookURL requires an absolute https URL, or http as well on dev
+// platforms.
+func (cfg *apiConfig) checkWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("url must be an absolute URL")
+	}
+	if u.Scheme != "https" && (u.Scheme != "http" || cfg.PLATFORM != "dev") {
+		return errors.New("url must use https")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handleListWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := cfg.dbQueries.ListWebhookEndpoints(r.Context(), auth.PrincipalFrom(r.Context()).UserID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list webhooks", err)
+		return
+	}
+
+	resp := make([]WebhookEndpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		resp = append(resp, newWebhookEndpoint(e))
+	}
+	helper.RespondWithJson(w, 200, resp)
+}
+
+// handleDeleteWebhookEndpoint removes an endpoint along with its queued and
+// past deliveries.
+func (cfg *apiConfig) handleDeleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid webhook id", err)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteWebhookEndpoint(r.Context(), database.DeleteWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: auth.PrincipalFrom(r.Context()).UserID,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to delete webhook", err)
+		return
+	}
+	if deleted == 0 {
+		helper.RespondWithError(w, 404, "webhook not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// ownWebhookEndpoint parses the endpointID path value and checks that the
+// caller owns it.
+func (cfg *apiConfig) ownWebhookEndpoint(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	endpointID, err := uuid.Parse(r.PathValue("endpointID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid webhook id", err)
+		return uuid.Nil, false
+	}
+
+	_, err = cfg.dbQueries.GetWebhookEndpoint(r.Context(), database.GetWebhookEndpointParams{
+		ID:     endpointID,
+		UserID: auth.PrincipalFrom(r.Context()).UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 404, "webhook not found", err)
+		return uuid.Nil, false
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to look up webhook", err)
+		return uuid.Nil, false
+	}
+	return endpointID, true
+}
+
+// handleListWebhookDeliveries lists an endpoint's deliveries newest first.
+// status narrows the list to pending, succeeded or dead deliveries.
+func (cfg *apiConfig) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpointID, ok := cfg.ownWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := pagination.Parse(r.URL.Query())
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != deliveryPending && status != deliverySucceeded && status != deliveryDead {
+		helper.RespondWithError(w, 400, "status must be pending, succeeded or dead", nil)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		EndpointID:     endpointID,
+		Status:         sql.NullString{String: status, Valid: status != ""},
+		AfterCreatedAt: page.AfterCreatedAt(),
+		AfterID:        page.AfterID(),
+		Limit:          page.FetchLimit(),
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list deliveries", err)
+		return
+	}
+
+	rows, nextCursor := pagination.Trim(page, rows, func(d database.WebhookDelivery) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+	})
+
+	resp := webhookDeliveryPage{Deliveries: make([]WebhookDelivery, 0, len(rows)), NextCursor: nextCursor}
+	for _, d := range rows {
+		resp.Deliveries = append(resp.Deliveries, newWebhookDelivery(d))
+	}
+	helper.RespondWithJson(w, 200, resp)
+}
+
+// handleReplayWebhookDelivery queues a delivery to be sent again straight
+// away with a fresh set of retries, whatever became of it before.
+func (cfg *apiConfig) handleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	endpointID, ok := cfg.ownWebhookEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid delivery id", err)
+		return
+	}
+
+	delivery, err := cfg.dbQueries.ReplayWebhookDelivery(r.Context(), database.ReplayWebhookDeliveryParams{
+		ID:            deliveryID,
+		EndpointID:    endpointID,
+		NextAttemptAt: time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 404, "delivery not found", err)
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to replay delivery", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 202, newWebhookDelivery(delivery))
+}
+
+// dispatchWebhooks sends queued deliveries as they come due, every interval
+// until ctx is done. Several instances can run it at once: each delivery is
+// leased to the instance that claims it.
+func (cfg *apiConfig) dispatchWebhooks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := cfg.dispatchWebhookBatch(ctx)
+			if err != nil {
+				log.Printf("unable to dispatch webhooks: %v", err)
+			}
+			if err != nil || n < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchWebhookBatch claims up to webhookBatchSize due deliveries and sends
+// them concurrently, returning how many it claimed.
+func (cfg *apiConfig) dispatchWebhookBatch(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	deliveries, err := cfg.dbQueries.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil: now.Add(webhookLease),
+		Now:        now,
+		Limit:      webhookBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.deliverWebhook(ctx, d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliverWebhook sends one delivery and records how it went, scheduling a
+// retry or dead-lettering it if it failed.
+func (cfg *apiConfig) deliverWebhook(ctx context.Context, d database.ClaimWebhookDeliveriesRow) {
+	status, sendErr := cfg.webhooks.Send(ctx, d.Url, d.Secret, d.EventType, d.ID.String(), []byte(d.Payload))
+	statusCode := sql.NullInt32{Int32: int32(status), Valid: status != 0}
+
+	if sendErr == nil {
+		if err := cfg.dbQueries.MarkWebhookDelivered(ctx, database.MarkWebhookDeliveredParams{
+			ID:             d.ID,
+			LastStatusCode: statusCode,
+		}); err != nil {
+			log.Printf("unable to record webhook delivery %s: %v", d.ID, err)
+		}
+		return
+	}
+
+	// Shutting down isn't the endpoint's fault; the lease will run out and
+	// another dispatcher will try again.
+	if ctx.Err() != nil {
+		return
+	}
+
+	next := deliveryPending
+	delay, retry := webhook.DefaultRetryPolicy.Next(int(d.Attempts) + 1)
+	if !retry {
+		next = deliveryDead
+		log.Printf("giving up on webhook delivery %s to %s: %v", d.ID, d.Url, sendErr)
+	}
+
+	if err := cfg.dbQueries.RecordWebhookFailure(ctx, database.RecordWebhookFailureParams{
+		ID:             d.ID,
+		Status:         next,
+		NextAttemptAt:  time.Now().UTC().Add(delay),
+		LastStatusCode: statusCode,
+		LastError:      sql.NullString{String: truncateError(sendErr), Valid: true},
+	}); err != nil {
+		log.Printf("unable to record failed webhook delivery %s: %v", d.ID, err)
+	}
+}
+
+// truncateError keeps error messages from endpoints a reasonable size.
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = strings.ToValidUTF8(msg[:500], "")
+	}
+	return msg
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/mailer"
+)
+
+// handleForgotPassword emails a single-use reset link. It answers 202 whether
+// or not the address belongs to an account so it can't be used to find out
+// who is registered.
+func (cfg *apiConfig) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	user, err := cfg.dbQueries.LoginUser(r.Context(), normalizeEmail(params.Email))
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithJson(w, 202, map[string]any{})
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to start password reset", err)
+		return
+	}
+
+	// The reset is created and mailed in the background so an existing
+	// account doesn't take measurably longer to answer for than a missing
+	// one.
+	go cfg.sendPasswordReset(context.WithoutCancel(r.Context()), user)
+
+	helper.RespondWithJson(w, 202, map[string]any{})
+}
+
+// sendPasswordReset creates a reset token for user and emails them the link.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, user database.User) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("unable to start password reset: %v", err)
+		return
+	}
+
+	if err := cfg.dbQueries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(cfg.PASSWORD_RESET_TTL),
+	}); err != nil {
+		log.Printf("unable to start password reset: %v", err)
+		return
+	}
+
+	link := cfg.APP_BASE_URL + "/reset-password?token=" + url.QueryEscape(token)
+	if err := cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your Chirpy account.\n\n"+
+				"Open this link within %s to choose a new one:\n%s\n\n"+
+				"If it wasn't you, you can ignore this email.\n",
+			cfg.PASSWORD_RESET_TTL, link,
+		),
+	}); err != nil {
+		log.Printf("unable to send password reset email: %v", err)
+	}
+}
+
+// handleResetPassword sets a new password using a token from
+// handleForgotPassword, then logs the user out everywhere and revokes their
+// API keys.
+func (cfg *apiConfig) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+	if params.Password == "" {
+		helper.RespondWithError(w, 400, "password is required", nil)
+		return
+	}
+
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to hash password", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	userID, err := qtx.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 400, "invalid or expired reset token", err)
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	if err := qtx.UpdatePassword(r.Context(), database.UpdatePasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	}); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	// Any other links that were sent are no longer needed.
+	if err := qtx.DeletePasswordResetTokens(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	if err := qtx.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+	if _, err := revokeAccessTokens(r.Context(), qtx, userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	// Whoever had the old password may also have minted API keys.
+	if err := qtx.RevokeUserAPIKeys(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to reset password", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/chirptext"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entitlements"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+const (
+	// scheduledChirpInterval is how often due scheduled chirps are
+	// published.
+	scheduledChirpInterval = 30 * time.Second
+	// maxScheduleAhead is the furthest ahead a chirp can be scheduled.
+	maxScheduleAhead = 365 * 24 * time.Hour
+)
+
+type ScheduledChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	PublishAt time.Time  `json:"publish_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	FailedAt  *time.Time `json:"failed_at,omitempty"`
+	Failure   string     `json:"failure,omitempty"`
+}
+
+func newScheduledChirp(s database.ScheduledChirp) ScheduledChirp {
+	chirp := ScheduledChirp{
+		ID:        s.ID,
+		Body:      s.Body,
+		PublishAt: s.PublishAt,
+		CreatedAt: s.CreatedAt,
+		Failure:   s.Failure.String,
+	}
+	if s.ParentID.Valid {
+		chirp.InReplyTo = &s.ParentID.UUID
+	}
+	if s.FailedAt.Valid {
+		chirp.FailedAt = &s.FailedAt.Time
+	}
+	return chirp
+}
+
+func (cfg *apiConfig) handleScheduleChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		PublishAt time.Time  `json:"publish_at"`
+	}
+
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	publishAt := params.PublishAt.UTC()
+	if !publishAt.After(now) || publishAt.Sub(now) > maxScheduleAhead {
+		helper.RespondWithError(w, 400, "publish_at must be in the next year", nil)
+		return
+	}
+
+	author, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+	if !author.EmailVerifiedAt.Valid {
+		helper.RespondWithError(w, 403, "verify your email address before posting", nil)
+		return
+	}
+
+	_, ent, err := cfg.entitlementsFor(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to schedule chirp", err)
+		return
+	}
+	if ent.ScheduledChirps == 0 {
+		helper.RespondWithError(w, 403, "your plan doesn't include scheduled chirps", nil)
+		return
+	}
+
+	pending, err := cfg.dbQueries.CountPendingScheduledChirps(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to schedule chirp", err)
+		return
+	}
+	if pending >= int64(ent.ScheduledChirps) {
+		helper.RespondWithError(w, 403, "too many scheduled chirps", nil)
+		return
+	}
+
+	// The body is checked now so the author hears about problems straight
+	// away; moderation runs again at publish time in case the rules change.
+	if _, ok := cfg.checkChirpBody(w, params.Body, ent.ChirpLength); !ok {
+		return
+	}
+
+	parentID := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.dbQueries.GetChirpById(r.Context(), *params.InReplyTo)
+		if err != nil || parent.DeletedAt.Valid {
+			helper.RespondWithError(w, 404, "chirp to reply to not found", err)
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	scheduled, err := cfg.dbQueries.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID:    userID,
+		Body:      params.Body,
+		ParentID:  parentID,
+		PublishAt: publishAt,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to schedule chirp", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 201, newScheduledChirp(scheduled))
+}
+
+func (cfg *apiConfig) handleListScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	rows, err := cfg.dbQueries.ListScheduledChirps(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list scheduled chirps", err)
+		return
+	}
+
+	chirps := make([]ScheduledChirp, 0, len(rows))
+	for _, s := range rows {
+		chirps = append(chirps, newScheduledChirp(s))
+	}
+
+	helper.RespondWithJson(w, 200, chirps)
+}
+
+func (cfg *apiConfig) handleDeleteScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid scheduled chirp id", err)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     scheduledID,
+		UserID: userID,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to delete scheduled chirp", err)
+		return
+	}
+	if deleted == 0 {
+		helper.RespondWithError(w, 404, "scheduled chirp not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// publishScheduledChirps publishes chirps as they come due, every interval
+// until ctx is done. Several instances can run it at once: each chirp is
+// claimed with a row lock that the others skip.
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := cfg.publishNextScheduledChirp(ctx)
+			if err != nil {
+				log.Printf("unable to publish scheduled chirp: %v", err)
+			}
+			if !published {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishNextScheduledChirp publishes one due chirp, reporting false when
+// there was none left. A chirp that can no longer be published is marked as
+// failed rather than retried forever, one that would take its author over
+// their hourly limit is put off until the limit allows it, and one that
+// couldn't be checked is put off until the next run.
+func (cfg *apiConfig) publishNextScheduledChirp(ctx context.Context) (bool, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	now := time.Now().UTC()
+	scheduled, err := qtx.ClaimDueScheduledChirp(ctx, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// The author's plan may have changed since the chirp was scheduled.
+	_, ent, err := cfg.entitlementsFor(ctx, scheduled.UserID)
+	if err != nil {
+		return cfg.retryScheduledChirp(ctx, tx, scheduled.ID, now, err)
+	}
+
+	failure, err := cfg.checkScheduledChirp(ctx, qtx, scheduled, ent)
+	if err != nil {
+		return cfg.retryScheduledChirp(ctx, tx, scheduled.ID, now, err)
+	}
+	if failure != "" {
+		if err := qtx.FailScheduledChirp(ctx, database.FailScheduledChirpParams{
+			ID:      scheduled.ID,
+			Failure: sql.NullString{String: failure, Valid: true},
+		}); err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	wait, err := chirpRateWait(ctx, qtx, scheduled.UserID, ent, now)
+	if err != nil {
+		return cfg.retryScheduledChirp(ctx, tx, scheduled.ID, now, err)
+	}
+	if wait > 0 {
+		if err := qtx.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+			ID:        scheduled.ID,
+			PublishAt: now.Add(wait),
+		}); err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	moderated := cfg.chirpFilter.Check(scheduled.Body)
+	_, err = insertChirp(ctx, qtx, scheduled.UserID, scheduled.ParentID, moderated)
+	if err == nil {
+		err = qtx.RemoveScheduledChirp(ctx, scheduled.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		// Left pending, the chirp would be claimed again straight away and
+		// hold up every chirp due after it. The transaction has to go first,
+		// since it is in no state to record anything.
+		tx.Rollback()
+		if ferr := cfg.dbQueries.FailScheduledChirp(ctx, database.FailScheduledChirpParams{
+			ID:      scheduled.ID,
+			Failure: sql.NullString{String: "unable to publish chirp", Valid: true},
+		}); ferr != nil {
+			return false, errors.Join(err, ferr)
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+// retryScheduledChirp puts off a chirp that couldn't be checked because of
+// err until the next run, so that it doesn't hold up every chirp due after
+// it in the meantime.
+func (cfg *apiConfig) retryScheduledChirp(ctx context.Context, tx *sql.Tx, id uuid.UUID, now time.Time, err error) (bool, error) {
+	tx.Rollback()
+	if perr := cfg.dbQueries.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+		ID:        id,
+		PublishAt: now.Add(scheduledChirpInterval),
+	}); perr != nil {
+		return false, errors.Join(err, perr)
+	}
+	return true, err
+}
+
+// checkScheduledChirp returns why scheduled can't be published under ent,
+// or "" if it can.
+func (cfg *apiConfig) checkScheduledChirp(ctx context.Context, q *database.Queries, scheduled database.ScheduledChirp, ent entitlements.Entitlements) (string, error) {
+	if err := chirptext.Validate(scheduled.Body, ent.ChirpLength); err != nil {
+		return "chirp is longer than your plan allows", nil
+	}
+	if cfg.chirpFilter.Check(scheduled.Body).Rejected {
+		return "chirp contains disallowed language", nil
+	}
+
+	author, err := q.GetUserById(ctx, scheduled.UserID)
+	if err != nil {
+		return "", err
+	}
+	if author.SuspendedAt.Valid {
+		return "account suspended", nil
+	}
+
+	if scheduled.ParentID.Valid {
+		parent, err := q.GetChirpById(ctx, scheduled.ParentID.UUID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && parent.DeletedAt.Valid) {
+			return "chirp being replied to was deleted", nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
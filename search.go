@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/pagination"
+	"github.com/thetsajeet/chirpy/internal/search"
+)
+
+type SearchResult struct {
+	Chirp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+func (cfg *apiConfig) SearchChirps(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Results    []SearchResult `json:"results"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}
+
+	query := r.URL.Query()
+
+	tsQuery, err := search.ToTSQuery(query.Get("q"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid search query", err)
+		return
+	}
+
+	page, err := pagination.ParseOffset(query)
+	if err != nil {
+		helper.RespondWithError(w, 400, err.Error(), err)
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Query:  tsQuery,
+		Limit:  page.FetchLimit(),
+		Offset: int32(page.Offset),
+	}
+
+	if author_id := query.Get("author_id"); author_id != "" {
+		id, err := uuid.Parse(author_id)
+		if err != nil {
+			helper.RespondWithError(w, 400, "invalid author_id", err)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if params.Since, err = parseTimeParam(query.Get("since")); err != nil {
+		helper.RespondWithError(w, 400, "invalid since, expected RFC 3339", err)
+		return
+	}
+	if params.Until, err = parseTimeParam(query.Get("until")); err != nil {
+		helper.RespondWithError(w, 400, "invalid until, expected RFC 3339", err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.SearchChirps(r.Context(), params)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to search chirps", err)
+		return
+	}
+
+	rows, nextCursor := pagination.TrimOffset(page, rows)
+
+	resp := response{Results: make([]SearchResult, 0, len(rows)), NextCursor: nextCursor}
+	for _, row := range rows {
+		resp.Results = append(resp.Results, SearchResult{
+			Chirp:   newChirp(row.Chirp),
+			Rank:    row.Rank,
+			Snippet: search.EscapeSnippet(row.Snippet),
+		})
+	}
+
+	refs := make([]*Chirp, 0, len(resp.Results))
+	for i := range resp.Results {
+		refs = append(refs, &resp.Results[i].Chirp)
+	}
+	if err := cfg.markLikedByMe(r.Context(), auth.PrincipalFrom(r.Context()).UserID, refs...); err != nil {
+		helper.RespondWithError(w, 500, "unable to search chirps", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+func parseTimeParam(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
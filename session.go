@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+)
+
+// Session is one logged-in device. It maps to a refresh token family: every
+// login starts a family and every refresh rotates the token within it.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (cfg *apiConfig) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	rows, err := cfg.dbQueries.ListSessions(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to list sessions", err)
+		return
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, s := range rows {
+		sessions = append(sessions, Session{
+			ID:         s.FamilyID,
+			UserAgent:  s.UserAgent,
+			IP:         s.Ip,
+			CreatedAt:  s.StartedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+		})
+	}
+
+	helper.RespondWithJson(w, 200, sessions)
+}
+
+// handleRevokeSession logs one session out. Access tokens issued to it stop
+// working along with its refresh token.
+func (cfg *apiConfig) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid session id", err)
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeSession(r.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke session", err)
+		return
+	}
+	if revoked == 0 {
+		helper.RespondWithError(w, 404, "session not found", nil)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// handleRevokeAllSessions logs the user out everywhere, including access
+// tokens that haven't expired yet, the caller's own among them.
+func (cfg *apiConfig) handleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke sessions", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	if err := qtx.RevokeAllUserTokens(r.Context(), userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke sessions", err)
+		return
+	}
+	if _, err := revokeAccessTokens(r.Context(), qtx, userID); err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke sessions", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to revoke sessions", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+// clientIP returns the address of the client that made the request. Proxy
+// headers are only trusted when TRUST_PROXY is set, since anyone can send them.
+// Each proxy appends the address it received the request from to
+// X-Forwarded-For, and everything left of what our own proxies added came
+// from the client, so the address is read TRUSTED_PROXY_HOPS entries from the
+// right.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	if cfg.TRUST_PROXY {
+		var hops []string
+		for _, fwd := range r.Header.Values("X-Forwarded-For") {
+			for _, hop := range strings.Split(fwd, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		if len(hops) > 0 {
+			return hops[max(len(hops)-cfg.TRUSTED_PROXY_HOPS, 0)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,26 @@
+-- name: CreateAPIKey :one
+insert into api_keys (id, user_id, name, prefix, key_hash, scopes, created_at, expires_at)
+values (gen_random_uuid(), $1, $2, $3, $4, $5, now(), $6)
+returning *;
+
+-- name: ListAPIKeys :many
+select *
+from api_keys
+where user_id = $1 and revoked_at is null
+order by created_at desc;
+
+-- name: RevokeAPIKey :execrows
+update api_keys
+set revoked_at = now()
+where id = $1 and user_id = $2 and revoked_at is null;
+
+-- name: UseAPIKey :one
+update api_keys
+set last_used_at = now()
+where key_hash = $1 and revoked_at is null and (expires_at is null or expires_at > now())
+returning id, user_id, scopes;
+
+-- name: RevokeUserAPIKeys :exec
+update api_keys
+set revoked_at = now()
+where user_id = $1 and revoked_at is null;
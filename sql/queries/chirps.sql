@@ -1,14 +1,29 @@
 -- name: CreateChirp :one
-insert into chirps (id, created_at, updated_at, body, user_id)
+insert into chirps (id, created_at, updated_at, body, user_id, parent_id)
 values (
-    gen_random_uuid(), now(), now(), $1, $2
+    gen_random_uuid(), now(), now(), $1, $2, $3
 )
 returning *;
 
--- name: GetAllChirps :many
-select * 
+-- name: ListChirpsAsc :many
+select *
 from chirps
-order by created_at;
+where deleted_at is null
+  and (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id'))
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (created_at, id) > (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by created_at, id
+limit sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+select *
+from chirps
+where deleted_at is null
+  and (sqlc.narg('author_id')::uuid is null or user_id = sqlc.narg('author_id'))
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (created_at, id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by created_at desc, id desc
+limit sqlc.arg('limit');
 
 -- name: GetChirpById :one
 select *
@@ -19,8 +34,72 @@ where id = $1;
 delete from chirps
 where id = $1 and user_id = $2;
 
--- name: GetChirpsByAuthorId :many
+-- name: TombstoneChirp :exec
+update chirps
+set body = '', deleted_at = now(), updated_at = now()
+where id = $1 and user_id = $2;
+
+-- name: ChirpHasReplies :one
+select exists (
+    select 1
+    from chirps
+    where parent_id = $1
+);
+
+-- name: GetThreadRootId :one
+with recursive ancestors as (
+    select id, parent_id, 0 as depth
+    from chirps
+    where chirps.id = $1
+    union all
+    select c.id, c.parent_id, ancestors.depth + 1
+    from chirps c
+    join ancestors on c.id = ancestors.parent_id
+)
+select id
+from ancestors
+order by depth desc
+limit 1;
+
+-- name: GetThread :many
+with recursive thread as (
+    select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count, 0 as depth
+    from chirps
+    where chirps.id = sqlc.arg('root_id')
+    union all
+    select c.id, c.created_at, c.updated_at, c.body, c.user_id, c.parent_id, c.deleted_at, c.like_count, c.rechirp_count, thread.depth + 1
+    from chirps c
+    join thread on c.parent_id = thread.id
+    where thread.depth < sqlc.arg('max_depth')::int
+)
+select id, created_at, updated_at, body, user_id, parent_id, deleted_at, like_count, rechirp_count, depth
+from thread
+order by depth, created_at, id
+limit sqlc.arg('max_nodes');
+
+-- name: GetChirpForUpdate :one
 select *
 from chirps
-where user_id = $1
-order by created_at;
\ No newline at end of file
+where id = $1
+for update;
+
+-- name: UpdateChirpBody :one
+update chirps
+set body = $2, updated_at = now()
+where id = $1
+returning *;
+
+-- name: CreateChirpRevision :exec
+insert into chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+values (gen_random_uuid(), $1, $2, $3, now());
+
+-- name: ListChirpRevisions :many
+select *
+from chirp_revisions
+where chirp_id = $1
+order by created_at desc, id desc;
+
+-- name: CountChirpsSince :one
+select count(*) as count, coalesce(min(created_at), sqlc.arg('since'))::timestamp as oldest
+from chirps
+where user_id = sqlc.arg('user_id') and created_at > sqlc.arg('since');
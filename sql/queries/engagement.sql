@@ -0,0 +1,22 @@
+-- name: LikeChirp :exec
+insert into chirp_likes (user_id, chirp_id, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: UnlikeChirp :exec
+delete from chirp_likes
+where user_id = $1 and chirp_id = $2;
+
+-- name: ListLikedChirpIds :many
+select chirp_id
+from chirp_likes
+where user_id = $1 and chirp_id = any(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: Rechirp :exec
+insert into rechirps (id, user_id, chirp_id, created_at)
+values (gen_random_uuid(), $1, $2, now())
+on conflict do nothing;
+
+-- name: UndoRechirp :exec
+delete from rechirps
+where user_id = $1 and chirp_id = $2;
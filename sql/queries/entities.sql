@@ -0,0 +1,41 @@
+-- name: AddChirpHashtags :exec
+insert into chirp_hashtags (chirp_id, tag)
+select sqlc.arg('chirp_id'), unnest(sqlc.arg('tags')::text[])
+on conflict do nothing;
+
+-- name: AddChirpMentions :exec
+insert into chirp_mentions (chirp_id, user_id)
+select sqlc.arg('chirp_id'), users.id
+from users
+where users.handle = any(sqlc.arg('handles')::text[])
+on conflict do nothing;
+
+-- name: ListChirpsByHashtag :many
+select chirps.*
+from chirps
+join chirp_hashtags on chirp_hashtags.chirp_id = chirps.id
+where chirp_hashtags.tag = sqlc.arg('tag')
+  and chirps.deleted_at is null
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg('limit');
+
+-- name: ListChirpsMentioningUser :many
+select chirps.*
+from chirps
+join chirp_mentions on chirp_mentions.chirp_id = chirps.id
+where chirp_mentions.user_id = sqlc.arg('user_id')
+  and chirps.deleted_at is null
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by chirps.created_at desc, chirps.id desc
+limit sqlc.arg('limit');
+
+-- name: DeleteChirpHashtags :exec
+delete from chirp_hashtags
+where chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+delete from chirp_mentions
+where chirp_id = $1;
@@ -0,0 +1,48 @@
+-- name: FollowUser :execrows
+insert into follows (follower_id, followee_id, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: UnfollowUser :exec
+delete from follows
+where follower_id = $1 and followee_id = $2;
+
+-- name: ListFollowers :many
+select users.id, users.handle, users.is_chirpy_red, follows.created_at as followed_at
+from follows
+join users on users.id = follows.follower_id
+where follows.followee_id = sqlc.arg('user_id')
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (follows.created_at, users.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by follows.created_at desc, users.id desc
+limit sqlc.arg('limit');
+
+-- name: ListFollowing :many
+select users.id, users.handle, users.is_chirpy_red, follows.created_at as followed_at
+from follows
+join users on users.id = follows.followee_id
+where follows.follower_id = sqlc.arg('user_id')
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (follows.created_at, users.id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by follows.created_at desc, users.id desc
+limit sqlc.arg('limit');
+
+-- name: GetTimeline :many
+select timeline.item_id, timeline.activity_at, timeline.rechirped_by, sqlc.embed(chirps)
+from (
+    select chirps.id as item_id, chirps.created_at as activity_at, null::uuid as rechirped_by, chirps.id as chirp_id
+    from chirps
+    join follows on follows.followee_id = chirps.user_id
+    where follows.follower_id = sqlc.arg('user_id')
+    union all
+    select rechirps.id, rechirps.created_at, rechirps.user_id, rechirps.chirp_id
+    from rechirps
+    join follows on follows.followee_id = rechirps.user_id
+    where follows.follower_id = sqlc.arg('user_id')
+) timeline
+join chirps on chirps.id = timeline.chirp_id
+where chirps.deleted_at is null
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (timeline.activity_at, timeline.item_id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by timeline.activity_at desc, timeline.item_id desc
+limit sqlc.arg('limit');
@@ -0,0 +1,36 @@
+-- name: GetLoginThrottle :one
+select *
+from login_throttles
+where key = $1;
+
+-- name: CreateLoginThrottle :exec
+-- Gives a new key a row for GetLoginThrottleForUpdate to lock.
+insert into login_throttles (key, failures, last_failure_at, locked_until)
+values ($1, 0, $2, null)
+on conflict (key) do nothing;
+
+-- name: GetLoginThrottleForUpdate :one
+select *
+from login_throttles
+where key = $1
+for update;
+
+-- name: UpdateLoginThrottle :exec
+update login_throttles
+set failures = $2, last_failure_at = $3, locked_until = $4
+where key = $1;
+
+-- name: ResetLoginThrottle :exec
+delete from login_throttles
+where key = $1;
+
+-- name: DeleteStaleLoginThrottles :exec
+-- now is passed in rather than read from the database clock, since
+-- locked_until was set from the application's.
+delete from login_throttles
+where last_failure_at < sqlc.arg('forget_before')
+  and (locked_until is null or locked_until < sqlc.arg('now'));
+
+-- name: CreateLoginLockout :exec
+insert into login_lockouts (id, key, user_id, ip, locked_until, created_at)
+values (gen_random_uuid(), $1, $2, $3, $4, now());
@@ -0,0 +1,32 @@
+-- name: SetTOTPSecret :exec
+update users
+set totp_secret = $2, totp_enabled_at = null, totp_last_step = 0, updated_at = now()
+where id = $1;
+
+-- name: EnableTOTP :exec
+update users
+set totp_enabled_at = now(), updated_at = now()
+where id = $1;
+
+-- name: DisableTOTP :exec
+update users
+set totp_secret = null, totp_enabled_at = null, totp_last_step = 0, updated_at = now()
+where id = $1;
+
+-- name: UseTOTPStep :execrows
+update users
+set totp_last_step = $2
+where id = $1 and totp_last_step < $2;
+
+-- name: CreateRecoveryCode :exec
+insert into recovery_codes (id, user_id, code_hash, created_at, used_at)
+values (gen_random_uuid(), $1, $2, now(), null);
+
+-- name: DeleteRecoveryCodes :exec
+delete from recovery_codes
+where user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+update recovery_codes
+set used_at = now()
+where user_id = $1 and code_hash = $2 and used_at is null;
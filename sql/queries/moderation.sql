@@ -0,0 +1,40 @@
+-- name: ListModerationWords :many
+select *
+from moderation_words
+order by word;
+
+-- name: UpsertModerationWord :exec
+insert into moderation_words (word, action, created_at, updated_at)
+values ($1, $2, now(), now())
+on conflict (word) do update
+set action = excluded.action, updated_at = now();
+
+-- name: SeedModerationWord :exec
+insert into moderation_words (word, action, created_at, updated_at)
+values ($1, $2, now(), now())
+on conflict do nothing;
+
+-- name: DeleteModerationWord :exec
+delete from moderation_words
+where word = $1;
+
+-- name: FlagChirp :exec
+insert into chirp_flags (chirp_id, word, created_at)
+values ($1, $2, now())
+on conflict do nothing;
+
+-- name: ListPendingFlags :many
+select chirp_flags.chirp_id, chirp_flags.word, chirp_flags.created_at, chirps.body, chirps.user_id
+from chirp_flags
+join chirps on chirps.id = chirp_flags.chirp_id
+where chirp_flags.reviewed_at is null
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (chirp_flags.created_at, chirp_flags.chirp_id, chirp_flags.word)
+          > (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid, sqlc.narg('after_word')::text))
+order by chirp_flags.created_at, chirp_flags.chirp_id, chirp_flags.word
+limit sqlc.arg('limit');
+
+-- name: ResolveChirpFlags :exec
+update chirp_flags
+set reviewed_at = now()
+where chirp_id = $1 and reviewed_at is null;
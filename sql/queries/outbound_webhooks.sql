@@ -0,0 +1,79 @@
+-- name: CreateWebhookEndpoint :one
+insert into webhook_endpoints (id, user_id, url, secret, events, all_users, created_at)
+values (gen_random_uuid(), $1, $2, $3, $4, $5, now())
+returning *;
+
+-- name: CountWebhookEndpoints :one
+select count(*)
+from webhook_endpoints
+where user_id = $1;
+
+-- name: ListWebhookEndpoints :many
+select *
+from webhook_endpoints
+where user_id = $1
+order by created_at, id;
+
+-- name: GetWebhookEndpoint :one
+select *
+from webhook_endpoints
+where id = $1 and user_id = $2;
+
+-- name: DeleteWebhookEndpoint :execrows
+delete from webhook_endpoints
+where id = $1 and user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :execrows
+insert into webhook_deliveries (id, endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+select gen_random_uuid(), id, sqlc.arg('event_id')::uuid, sqlc.arg('event_type')::text, sqlc.arg('payload')::text, 'pending', sqlc.arg('next_attempt_at')::timestamp, now()
+from webhook_endpoints
+where sqlc.arg('event_type')::text = any(events)
+  and (all_users or user_id = any(sqlc.arg('user_ids')::uuid[]));
+
+-- name: ClaimWebhookDeliveries :many
+-- Claimed deliveries are leased until lease_until rather than locked, so
+-- slow endpoints don't hold a transaction open. If the sender dies, the
+-- delivery comes due again when the lease runs out. now is passed in rather
+-- than read from the database clock, since next_attempt_at is set from the
+-- application's.
+update webhook_deliveries
+set next_attempt_at = sqlc.arg('lease_until')
+from webhook_endpoints
+where webhook_endpoints.id = webhook_deliveries.endpoint_id
+  and webhook_deliveries.id in (
+    select id
+    from webhook_deliveries
+    where status = 'pending' and next_attempt_at <= sqlc.arg('now')
+    order by next_attempt_at
+    limit sqlc.arg('limit')
+    for update skip locked
+  )
+returning webhook_deliveries.id, webhook_deliveries.event_type, webhook_deliveries.payload, webhook_deliveries.attempts, webhook_endpoints.url, webhook_endpoints.secret;
+
+-- name: MarkWebhookDelivered :exec
+update webhook_deliveries
+set status = 'succeeded', attempts = attempts + 1, last_attempt_at = now(),
+    last_status_code = $2, last_error = null, delivered_at = now()
+where id = $1;
+
+-- name: RecordWebhookFailure :exec
+update webhook_deliveries
+set status = $2, attempts = attempts + 1, next_attempt_at = $3,
+    last_attempt_at = now(), last_status_code = $4, last_error = $5
+where id = $1;
+
+-- name: ListWebhookDeliveries :many
+select *
+from webhook_deliveries
+where endpoint_id = sqlc.arg('endpoint_id')
+  and (sqlc.narg('status')::text is null or status = sqlc.narg('status'))
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (created_at, id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by created_at desc, id desc
+limit sqlc.arg('limit');
+
+-- name: ReplayWebhookDelivery :one
+update webhook_deliveries
+set status = 'pending', attempts = 0, next_attempt_at = $3, delivered_at = null
+where id = $1 and endpoint_id = $2
+returning *;
@@ -0,0 +1,13 @@
+-- name: CreatePasswordResetToken :exec
+insert into password_reset_tokens (token_hash, user_id, created_at, expires_at, used_at)
+values ($1, $2, now(), $3, null);
+
+-- name: UsePasswordResetToken :one
+update password_reset_tokens
+set used_at = now()
+where token_hash = $1 and used_at is null and expires_at > now()
+returning user_id;
+
+-- name: DeletePasswordResetTokens :exec
+delete from password_reset_tokens
+where user_id = $1;
@@ -0,0 +1,43 @@
+-- name: CreateScheduledChirp :one
+insert into scheduled_chirps (id, user_id, body, parent_id, publish_at, created_at)
+values (gen_random_uuid(), $1, $2, $3, $4, now())
+returning *;
+
+-- name: CountPendingScheduledChirps :one
+select count(*)
+from scheduled_chirps
+where user_id = $1 and failed_at is null;
+
+-- name: ListScheduledChirps :many
+select *
+from scheduled_chirps
+where user_id = $1
+order by publish_at, id;
+
+-- name: DeleteScheduledChirp :execrows
+delete from scheduled_chirps
+where id = $1 and user_id = $2;
+
+-- name: ClaimDueScheduledChirp :one
+-- now is passed in rather than read from the database clock, since
+-- publish_at was set from the application's.
+select *
+from scheduled_chirps
+where publish_at <= sqlc.arg('now') and failed_at is null
+order by publish_at
+limit 1
+for update skip locked;
+
+-- name: PostponeScheduledChirp :exec
+update scheduled_chirps
+set publish_at = $2
+where id = $1;
+
+-- name: RemoveScheduledChirp :exec
+delete from scheduled_chirps
+where id = $1;
+
+-- name: FailScheduledChirp :exec
+update scheduled_chirps
+set failed_at = now(), failure = $2
+where id = $1;
@@ -0,0 +1,13 @@
+-- name: SearchChirps :many
+select sqlc.embed(chirps),
+       ts_rank_cd(to_tsvector('english', chirps.body), query)::real as rank,
+       ts_headline('english', chirps.body, query,
+                   'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5')::text as snippet
+from chirps, to_tsquery('english', sqlc.arg('query')) query
+where chirps.deleted_at is null
+  and to_tsvector('english', chirps.body) @@ query
+  and (sqlc.narg('author_id')::uuid is null or chirps.user_id = sqlc.narg('author_id'))
+  and (sqlc.narg('since')::timestamp is null or chirps.created_at >= sqlc.narg('since'))
+  and (sqlc.narg('until')::timestamp is null or chirps.created_at < sqlc.narg('until'))
+order by rank desc, chirps.created_at desc, chirps.id desc
+limit sqlc.arg('limit') offset sqlc.arg('offset');
@@ -0,0 +1,14 @@
+-- name: CreateSigningKey :exec
+insert into signing_keys (id, algorithm, private_key, created_at, retired_at)
+values ($1, $2, $3, $4, null);
+
+-- name: RetireSigningKeys :exec
+update signing_keys
+set retired_at = now()
+where retired_at is null and id <> $1;
+
+-- name: ListSigningKeys :many
+select *
+from signing_keys
+where retired_at is null or retired_at > $1
+order by created_at desc;
@@ -0,0 +1,37 @@
+-- name: GetSubscription :one
+select *
+from subscriptions
+where user_id = $1;
+
+-- name: GetSubscriptionForUpdate :one
+select *
+from subscriptions
+where user_id = $1
+for update;
+
+-- name: UpsertSubscription :one
+insert into subscriptions (user_id, plan, status, current_period_start, current_period_end, last_event_at, created_at, updated_at)
+values ($1, $2, $3, $4, $5, $6, now(), now())
+on conflict (user_id) do update
+set plan = excluded.plan,
+    status = excluded.status,
+    current_period_start = excluded.current_period_start,
+    current_period_end = excluded.current_period_end,
+    last_event_at = excluded.last_event_at,
+    updated_at = now()
+returning *;
+
+-- name: ExpireSubscriptions :many
+-- now is passed in rather than read from the database clock, since
+-- current_period_end was set from the application's.
+with expired as (
+    update subscriptions
+    set status = 'expired', updated_at = now()
+    where status <> 'expired' and current_period_end < sqlc.arg('now')
+    returning user_id
+)
+update users
+set is_chirpy_red = false, updated_at = now()
+from expired
+where users.id = expired.user_id
+returning users.id;
@@ -1,6 +1,6 @@
 -- name: StoreRefreshToken :exec
-insert into refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
-values ($1, now(), now(), $2, $3, null);
+insert into refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip, last_used_at)
+values ($1, now(), now(), $2, $3, null, $4, $5, $6, now());
 
 -- name: LookupToken :one
 select *
@@ -10,4 +10,40 @@ where token = $1;
 -- name: RevokeToken :exec
 update refresh_tokens
 set updated_at = now(), revoked_at = now()
-where token = $1;
\ No newline at end of file
+where token = $1;
+
+-- name: RotateRefreshToken :one
+update refresh_tokens
+set updated_at = now(), revoked_at = now(), replaced_by = $2
+where token = $1 and revoked_at is null and expires_at > now()
+returning *;
+
+-- name: RevokeTokenFamily :exec
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where family_id = $1 and revoked_at is null;
+
+-- name: ListSessions :many
+select family_id, user_agent, ip, last_used_at, expires_at,
+       (select min(f.created_at) from refresh_tokens f where f.family_id = refresh_tokens.family_id)::timestamp as started_at
+from refresh_tokens
+where user_id = $1 and revoked_at is null and expires_at > now()
+order by last_used_at desc;
+
+-- name: RevokeSession :execrows
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where family_id = $1 and user_id = $2 and revoked_at is null;
+
+-- name: RevokeAllUserTokens :exec
+update refresh_tokens
+set updated_at = now(), revoked_at = now()
+where user_id = $1 and revoked_at is null;
+
+-- name: SessionIsActive :one
+-- A session stays active while any token in its family is unrevoked.
+select exists (
+    select 1
+    from refresh_tokens
+    where family_id = $1 and user_id = $2 and revoked_at is null
+);
@@ -1,9 +1,9 @@
 -- name: CreateUser :one
-insert into users (id, created_at, updated_at, email, hashed_password)
+insert into users (id, created_at, updated_at, email, hashed_password, handle)
 values (
-    gen_random_uuid(), now(), now(), $1, $2
+    gen_random_uuid(), now(), now(), $1, $2, $3
 )
-returning id, created_at, updated_at, email, is_chirpy_red;
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at;
 
 -- name: DeleteAllUsers :exec
 delete from users;
@@ -15,11 +15,78 @@ where email = $1;
 
 -- name: UpdateUser :one
 update users
-set email = $1, hashed_password = $2, updated_at = now()
-where id = $3
-returning id, created_at, updated_at, email, is_chirpy_red;
+set email = sqlc.arg('email'),
+    hashed_password = sqlc.arg('hashed_password'),
+    handle = coalesce(sqlc.narg('handle'), handle),
+    email_verified_at = case when email = sqlc.arg('email') then email_verified_at end,
+    updated_at = now()
+where id = sqlc.arg('id')
+returning id, created_at, updated_at, email, is_chirpy_red, handle, email_verified_at;
 
--- name: UpdateChirpyRed :exec
+-- name: UpdateChirpyRed :execrows
 update users
-set is_chirpy_red = true
-where id = $1;
\ No newline at end of file
+set is_chirpy_red = $2, updated_at = now()
+where id = $1;
+
+-- name: GetUserById :one
+select *
+from users
+where id = $1;
+
+
+-- name: UpdatePassword :exec
+update users
+set hashed_password = $2, updated_at = now()
+where id = $1;
+
+-- name: MarkEmailVerified :execrows
+update users
+set email_verified_at = now(), updated_at = now()
+where id = $1 and email = $2 and email_verified_at is null;
+
+-- name: GetUserAccess :one
+select role, suspended_at, tokens_revoked_at
+from users
+where id = $1;
+
+-- name: ListUsers :many
+select id, created_at, email, handle, role, is_chirpy_red, email_verified_at, suspended_at
+from users
+where (sqlc.narg('query')::text is null
+       or strpos(email, lower(sqlc.narg('query'))) > 0
+       or strpos(lower(handle), lower(sqlc.narg('query'))) > 0)
+  and (sqlc.narg('after_created_at')::timestamp is null
+       or (created_at, id) < (sqlc.narg('after_created_at'), sqlc.narg('after_id')::uuid))
+order by created_at desc, id desc
+limit sqlc.arg('limit');
+
+-- name: SetUserRole :execrows
+update users
+set role = $2, updated_at = now()
+where id = $1;
+
+-- name: PromoteAdmins :exec
+update users
+set role = 'admin', updated_at = now()
+where email = any(sqlc.arg('emails')::text[]) and role <> 'admin';
+
+-- name: SuspendUser :execrows
+update users
+set suspended_at = coalesce(suspended_at, now()), updated_at = now()
+where id = $1;
+
+-- name: UnsuspendUser :execrows
+update users
+set suspended_at = null, updated_at = now()
+where id = $1;
+
+-- name: RevokeUserAccessTokens :execrows
+update users
+set tokens_revoked_at = $2
+where id = $1;
+
+-- name: GetUserPlan :one
+select users.is_chirpy_red, subscriptions.plan
+from users
+left join subscriptions on subscriptions.user_id = users.id
+where users.id = $1;
@@ -0,0 +1,4 @@
+-- name: RecordWebhookEvent :execrows
+insert into webhook_events (source, event_id, event_type, received_at)
+values ($1, $2, $3, now())
+on conflict (source, event_id) do nothing;
@@ -0,0 +1,17 @@
+-- +goose Up
+alter table chirps
+add column parent_id uuid references chirps (id) on delete set null;
+
+alter table chirps
+add column deleted_at timestamp;
+
+create index chirps_parent_id_idx on chirps (parent_id);
+
+-- +goose Down
+drop index chirps_parent_id_idx;
+
+alter table chirps
+drop column deleted_at;
+
+alter table chirps
+drop column parent_id;
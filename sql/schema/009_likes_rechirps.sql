@@ -0,0 +1,73 @@
+-- +goose Up
+alter table chirps
+add column like_count integer not null default 0;
+
+alter table chirps
+add column rechirp_count integer not null default 0;
+
+create table chirp_likes (
+    user_id uuid not null references users (id) on delete cascade,
+    chirp_id uuid not null references chirps (id) on delete cascade,
+    created_at timestamp not null,
+    primary key (user_id, chirp_id)
+);
+
+create table rechirps (
+    id uuid primary key,
+    user_id uuid not null references users (id) on delete cascade,
+    chirp_id uuid not null references chirps (id) on delete cascade,
+    created_at timestamp not null,
+    unique (user_id, chirp_id)
+);
+
+create index rechirps_user_id_created_at_idx on rechirps (user_id, created_at, id);
+
+-- Counters are maintained by row-level triggers so they stay correct under
+-- concurrent likes and when likes disappear through cascading deletes.
+-- +goose StatementBegin
+create function chirp_likes_count() returns trigger as $$
+begin
+    if tg_op = 'INSERT' then
+        update chirps set like_count = like_count + 1 where id = new.chirp_id;
+    elsif tg_op = 'DELETE' then
+        update chirps set like_count = like_count - 1 where id = old.chirp_id;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create function rechirps_count() returns trigger as $$
+begin
+    if tg_op = 'INSERT' then
+        update chirps set rechirp_count = rechirp_count + 1 where id = new.chirp_id;
+    elsif tg_op = 'DELETE' then
+        update chirps set rechirp_count = rechirp_count - 1 where id = old.chirp_id;
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+-- +goose StatementEnd
+
+create trigger chirp_likes_count
+after insert or delete on chirp_likes
+for each row execute function chirp_likes_count();
+
+create trigger rechirps_count
+after insert or delete on rechirps
+for each row execute function rechirps_count();
+
+-- +goose Down
+drop trigger rechirps_count on rechirps;
+drop trigger chirp_likes_count on chirp_likes;
+drop function rechirps_count;
+drop function chirp_likes_count;
+drop table rechirps;
+drop table chirp_likes;
+
+alter table chirps
+drop column rechirp_count;
+
+alter table chirps
+drop column like_count;
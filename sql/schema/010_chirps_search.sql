@@ -0,0 +1,6 @@
+-- +goose Up
+create index chirps_body_search_idx on chirps
+using gin (to_tsvector('english', body));
+
+-- +goose Down
+drop index chirps_body_search_idx;
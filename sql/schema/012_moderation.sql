@@ -0,0 +1,25 @@
+-- +goose Up
+create table moderation_words (
+    word text primary key,
+    action text not null check (action in ('mask', 'reject', 'flag')),
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+
+insert into moderation_words (word, action, created_at, updated_at)
+values
+    ('kerfuffle', 'mask', now(), now()),
+    ('sharbert', 'mask', now(), now()),
+    ('fornax', 'mask', now(), now());
+
+create table chirp_flags (
+    chirp_id uuid not null references chirps (id) on delete cascade,
+    word text not null,
+    created_at timestamp not null,
+    reviewed_at timestamp,
+    primary key (chirp_id, word)
+);
+
+-- +goose Down
+drop table chirp_flags;
+drop table moderation_words;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Chirp length is validated in the application, which counts grapheme
+-- clusters and weights links; varchar(140) counts code points and disagrees.
+alter table chirps
+alter column body type text;
+
+-- +goose Down
+-- Bodies longer than 140 characters are cut short; there is no way back to
+-- varchar(140) that keeps them.
+alter table chirps
+alter column body type varchar(140) using left(body, 140);
@@ -0,0 +1,13 @@
+-- +goose Up
+create table chirp_revisions (
+    id uuid primary key,
+    chirp_id uuid not null references chirps (id) on delete cascade,
+    body text not null,
+    created_at timestamp not null,
+    replaced_at timestamp not null
+);
+
+create index chirp_revisions_chirp_id_idx on chirp_revisions (chirp_id, created_at);
+
+-- +goose Down
+drop table chirp_revisions;
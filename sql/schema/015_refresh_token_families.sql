@@ -0,0 +1,23 @@
+-- +goose Up
+alter table refresh_tokens
+add column family_id uuid;
+
+update refresh_tokens
+set family_id = gen_random_uuid();
+
+alter table refresh_tokens
+alter column family_id set not null;
+
+alter table refresh_tokens
+add column replaced_by text;
+
+create index refresh_tokens_family_id_idx on refresh_tokens (family_id);
+
+-- +goose Down
+drop index refresh_tokens_family_id_idx;
+
+alter table refresh_tokens
+drop column replaced_by;
+
+alter table refresh_tokens
+drop column family_id;
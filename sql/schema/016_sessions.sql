@@ -0,0 +1,29 @@
+-- +goose Up
+alter table refresh_tokens
+add column user_agent text not null default '';
+
+alter table refresh_tokens
+add column ip text not null default '';
+
+alter table refresh_tokens
+add column last_used_at timestamp;
+
+update refresh_tokens
+set last_used_at = updated_at;
+
+alter table refresh_tokens
+alter column last_used_at set not null;
+
+create index refresh_tokens_user_id_idx on refresh_tokens (user_id);
+
+-- +goose Down
+drop index refresh_tokens_user_id_idx;
+
+alter table refresh_tokens
+drop column last_used_at;
+
+alter table refresh_tokens
+drop column ip;
+
+alter table refresh_tokens
+drop column user_agent;
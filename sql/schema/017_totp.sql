@@ -0,0 +1,32 @@
+-- +goose Up
+alter table users
+add column totp_secret text;
+
+alter table users
+add column totp_enabled_at timestamp;
+
+-- totp_last_step is the last accepted time step, so a code can't be replayed
+-- within its validity window.
+alter table users
+add column totp_last_step bigint not null default 0;
+
+create table recovery_codes (
+    id uuid primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    code_hash text not null,
+    created_at timestamp not null,
+    used_at timestamp,
+    unique (user_id, code_hash)
+);
+
+-- +goose Down
+drop table recovery_codes;
+
+alter table users
+drop column totp_last_step;
+
+alter table users
+drop column totp_enabled_at;
+
+alter table users
+drop column totp_secret;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Emails are compared case-insensitively from now on. If two accounts only
+-- differ by the case of their address they have to be merged by hand before
+-- the unique index below can be built.
+update users
+set email = lower(trim(email));
+
+create unique index users_email_idx on users (email);
+
+-- Accounts that existed before verification was introduced are trusted.
+alter table users
+add column email_verified_at timestamp;
+
+update users
+set email_verified_at = created_at;
+
+create table email_verification_tokens (
+    token_hash text primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    email text not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    used_at timestamp
+);
+
+create index email_verification_tokens_user_id_idx on email_verification_tokens (user_id);
+
+-- +goose Down
+drop table email_verification_tokens;
+
+alter table users
+drop column email_verified_at;
+
+drop index users_email_idx;
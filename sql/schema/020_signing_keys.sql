@@ -0,0 +1,12 @@
+-- +goose Up
+create table signing_keys (
+    id text primary key,
+    algorithm text not null,
+    -- PKCS #8 DER encoded private key.
+    private_key bytea not null,
+    created_at timestamp not null,
+    retired_at timestamp
+);
+
+-- +goose Down
+drop table signing_keys;
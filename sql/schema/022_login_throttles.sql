@@ -0,0 +1,22 @@
+-- +goose Up
+create table login_throttles (
+    key text primary key,
+    failures int not null,
+    last_failure_at timestamp not null,
+    locked_until timestamp
+);
+
+create table login_lockouts (
+    id uuid primary key,
+    key text not null,
+    user_id uuid references users(id) on delete set null,
+    ip text not null,
+    locked_until timestamp not null,
+    created_at timestamp not null
+);
+
+create index login_lockouts_created_at_idx on login_lockouts (created_at);
+
+-- +goose Down
+drop table login_lockouts;
+drop table login_throttles;
@@ -0,0 +1,22 @@
+-- +goose Up
+alter table users
+add column role text not null default 'user'
+check (role in ('user', 'moderator', 'admin'));
+
+alter table users
+add column suspended_at timestamp;
+
+-- Access tokens issued before tokens_revoked_at are rejected, so a forced
+-- logout takes effect before they expire.
+alter table users
+add column tokens_revoked_at timestamp;
+
+-- +goose Down
+alter table users
+drop column tokens_revoked_at;
+
+alter table users
+drop column suspended_at;
+
+alter table users
+drop column role;
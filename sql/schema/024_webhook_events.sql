@@ -0,0 +1,13 @@
+-- +goose Up
+-- webhook_events records every inbound webhook event we have processed, so
+-- a redelivery of the same event is acknowledged without acting twice.
+create table webhook_events (
+    source text not null,
+    event_id text not null,
+    event_type text not null,
+    received_at timestamp not null,
+    primary key (source, event_id)
+);
+
+-- +goose Down
+drop table webhook_events;
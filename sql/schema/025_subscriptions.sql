@@ -0,0 +1,26 @@
+-- +goose Up
+-- subscriptions holds each user's Chirpy Red subscription as reported by
+-- Polka. users.is_chirpy_red stays the flag the rest of the app reads; it is
+-- kept in step with the subscription's status.
+create table subscriptions (
+    user_id uuid primary key references users(id) on delete cascade,
+    plan text not null,
+    status text not null check (status in ('active', 'past_due', 'cancelled', 'expired')),
+    current_period_start timestamp not null,
+    current_period_end timestamp,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+
+create index subscriptions_current_period_end_idx on subscriptions (current_period_end)
+where status <> 'expired';
+
+-- Upgrades from before subscriptions were tracked have no known end, so they
+-- don't expire until Polka tells us otherwise.
+insert into subscriptions (user_id, plan, status, current_period_start, current_period_end, created_at, updated_at)
+select id, 'chirpy_red', 'active', updated_at, null, now(), now()
+from users
+where is_chirpy_red;
+
+-- +goose Down
+drop table subscriptions;
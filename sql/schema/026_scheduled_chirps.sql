@@ -0,0 +1,23 @@
+-- +goose Up
+-- scheduled_chirps are chirps waiting to be published at publish_at. Rows
+-- are deleted once published; ones that can't be published are kept with
+-- the reason so their author can see what happened.
+create table scheduled_chirps (
+    id uuid primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    body text not null,
+    -- No foreign key: a reply whose parent is deleted before it goes out
+    -- should fail, not quietly become a top-level chirp.
+    parent_id uuid,
+    publish_at timestamp not null,
+    created_at timestamp not null,
+    failed_at timestamp,
+    failure text
+);
+
+create index scheduled_chirps_user_id_idx on scheduled_chirps (user_id);
+create index scheduled_chirps_due_idx on scheduled_chirps (publish_at)
+where failed_at is null;
+
+-- +goose Down
+drop table scheduled_chirps;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- webhook_endpoints are URLs users want events sent to. An endpoint gets
+-- the events that involve its owner, or every event when all_users is set,
+-- which only admins can do. The secret is kept in the clear because it is
+-- needed to sign each delivery.
+create table webhook_endpoints (
+    id uuid primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    url text not null,
+    secret text not null,
+    events text[] not null,
+    all_users boolean not null default false,
+    created_at timestamp not null
+);
+
+create index webhook_endpoints_user_id_idx on webhook_endpoints (user_id);
+
+-- webhook_deliveries is the queue of events waiting to be sent to each
+-- endpoint, and the record of how sending them went. payload is text rather
+-- than jsonb so the bytes that are signed are the bytes that were queued.
+create table webhook_deliveries (
+    id uuid primary key,
+    endpoint_id uuid not null references webhook_endpoints(id) on delete cascade,
+    event_id uuid not null,
+    event_type text not null,
+    payload text not null,
+    status text not null check (status in ('pending', 'succeeded', 'dead')),
+    attempts integer not null default 0,
+    next_attempt_at timestamp not null,
+    last_attempt_at timestamp,
+    last_status_code integer,
+    last_error text,
+    created_at timestamp not null,
+    delivered_at timestamp
+);
+
+create index webhook_deliveries_endpoint_idx on webhook_deliveries (endpoint_id, created_at desc, id desc);
+create index webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at)
+where status = 'pending';
+
+-- +goose Down
+drop table webhook_deliveries;
+drop table webhook_endpoints;
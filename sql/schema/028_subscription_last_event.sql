@@ -0,0 +1,7 @@
+-- +goose Up
+-- last_event_at is when the latest Polka event applied to a subscription
+-- happened, so an older event delivered late doesn't undo a newer one.
+alter table subscriptions add column last_event_at timestamp;
+
+-- +goose Down
+alter table subscriptions drop column last_event_at;
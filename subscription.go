@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/subscription"
+)
+
+// subscriptionExpiryInterval is how often lapsed subscriptions are expired.
+const subscriptionExpiryInterval = time.Minute
+
+var errUnknownUser = errors.New("user not found")
+
+type Subscription struct {
+	Plan               string     `json:"plan"`
+	Status             string     `json:"status"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   *time.Time `json:"current_period_end"`
+}
+
+func (cfg *apiConfig) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	sub, err := cfg.dbQueries.GetSubscription(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 404, "no subscription", err)
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to get subscription", err)
+		return
+	}
+
+	resp := Subscription{
+		Plan:               sub.Plan,
+		Status:             sub.Status,
+		CurrentPeriodStart: sub.CurrentPeriodStart,
+	}
+	if sub.CurrentPeriodEnd.Valid {
+		resp.CurrentPeriodEnd = &sub.CurrentPeriodEnd.Time
+		// The expiry job may not have caught up yet.
+		if sub.CurrentPeriodEnd.Time.Before(time.Now().UTC()) {
+			resp.Status = subscription.Expired
+		}
+	}
+
+	helper.RespondWithJson(w, 200, resp)
+}
+
+// applySubscriptionEvent updates a user's subscription, and the Chirpy Red
+// flag that goes with it, for one Polka event. Unknown events, and ones older
+// than the last event applied, are ignored.
+func applySubscriptionEvent(ctx context.Context, q *database.Queries, p polkaEvent) error {
+	now := time.Now().UTC()
+
+	current := subscription.State{}
+	row, err := q.GetSubscriptionForUpdate(ctx, p.Data.UserId)
+	if err == nil {
+		current = subscription.State{
+			Plan:        row.Plan,
+			Status:      row.Status,
+			PeriodStart: row.CurrentPeriodStart,
+			PeriodEnd:   row.CurrentPeriodEnd.Time,
+			LastEventAt: row.LastEventAt.Time,
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// Events without a timestamp are taken to have just happened.
+	event := subscription.Event{Type: p.Event, At: now, Plan: p.Data.Plan}
+	if p.CreatedAt != nil {
+		event.At = p.CreatedAt.UTC()
+	}
+	if p.Data.PeriodStart != nil {
+		event.PeriodStart = p.Data.PeriodStart.UTC()
+	}
+	if p.Data.PeriodEnd != nil {
+		event.PeriodEnd = p.Data.PeriodEnd.UTC()
+	}
+
+	next, ok := subscription.Apply(current, event, now)
+	if !ok {
+		return nil
+	}
+	// This also finds out whether the user exists, which matters even when
+	// they have no subscription to change.
+	if err := setChirpyRed(ctx, q, p.Data.UserId, next.Red(now)); err != nil {
+		return err
+	}
+	if !next.Exists() {
+		return nil
+	}
+	_, err = q.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+		UserID:             p.Data.UserId,
+		Plan:               next.Plan,
+		Status:             next.Status,
+		CurrentPeriodStart: next.PeriodStart,
+		CurrentPeriodEnd:   sql.NullTime{Time: next.PeriodEnd, Valid: !next.PeriodEnd.IsZero()},
+		LastEventAt:        sql.NullTime{Time: next.LastEventAt, Valid: true},
+	})
+	return err
+}
+
+func setChirpyRed(ctx context.Context, q *database.Queries, userID uuid.UUID, red bool) error {
+	updated, err := q.UpdateChirpyRed(ctx, database.UpdateChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: red,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errUnknownUser
+	}
+	return nil
+}
+
+// expireSubscriptions takes Chirpy Red away from users whose paid period has
+// ended, every interval until ctx is done.
+func (cfg *apiConfig) expireSubscriptions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := cfg.dbQueries.ExpireSubscriptions(ctx, time.Now().UTC())
+		if err != nil {
+			log.Printf("unable to expire subscriptions: %v", err)
+		} else if len(expired) > 0 {
+			log.Printf("expired %d subscriptions", len(expired))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
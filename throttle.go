@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/lockout"
+)
+
+// accountThrottlePolicy limits guesses against a single account, whichever
+// addresses they come from.
+var accountThrottlePolicy = lockout.Policy{
+	FreeAttempts: 3,
+	BaseDelay:    time.Second,
+	MaxDelay:     time.Minute,
+	LockoutAfter: 10,
+	LockoutFor:   15 * time.Minute,
+	Window:       time.Hour,
+}
+
+// ipThrottlePolicy limits a single address spraying guesses across many
+// accounts. It is more lenient because several users can share an address.
+var ipThrottlePolicy = lockout.Policy{
+	FreeAttempts: 20,
+	BaseDelay:    time.Second,
+	MaxDelay:     time.Minute,
+	LockoutAfter: 100,
+	LockoutFor:   time.Hour,
+	Window:       time.Hour,
+}
+
+// newThrottleStore picks where failed logins are counted from
+// LOGIN_THROTTLE_STORE. "memory" (the default) only works with a single
+// instance; "postgres" shares the counts between instances.
+func newThrottleStore(db *sql.DB, q *database.Queries) lockout.Store {
+	switch os.Getenv("LOGIN_THROTTLE_STORE") {
+	case "", "memory":
+		return lockout.NewMemoryStore()
+	case "postgres":
+		return &pgThrottleStore{db: db, q: q}
+	default:
+		log.Fatalf("LOGIN_THROTTLE_STORE must be memory or postgres, got %q", os.Getenv("LOGIN_THROTTLE_STORE"))
+		return nil
+	}
+}
+
+// pgThrottleStore keeps failed login counts in the login_throttles table.
+// Times are stored in UTC since the columns have no time zone.
+type pgThrottleStore struct {
+	db        *sql.DB
+	q         *database.Queries
+	lastSweep atomic.Int64
+}
+
+func (s *pgThrottleStore) Get(ctx context.Context, key string) (lockout.State, error) {
+	row, err := s.q.GetLoginThrottle(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return lockout.State{}, nil
+	} else if err != nil {
+		return lockout.State{}, err
+	}
+	return throttleState(row), nil
+}
+
+// Update holds a row lock on key while update runs, so attempts on the
+// same key take turns across every instance.
+func (s *pgThrottleStore) Update(ctx context.Context, key string, now, forgetBefore time.Time, update func(lockout.State) lockout.State) (lockout.State, error) {
+	s.sweep(ctx, now, forgetBefore)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return lockout.State{}, err
+	}
+	defer tx.Rollback()
+	qtx := s.q.WithTx(tx)
+
+	if err := qtx.CreateLoginThrottle(ctx, database.CreateLoginThrottleParams{
+		Key:           key,
+		LastFailureAt: now.UTC(),
+	}); err != nil {
+		return lockout.State{}, err
+	}
+	row, err := qtx.GetLoginThrottleForUpdate(ctx, key)
+	if err != nil {
+		return lockout.State{}, err
+	}
+
+	st := update(throttleState(row))
+	if err := qtx.UpdateLoginThrottle(ctx, database.UpdateLoginThrottleParams{
+		Key:           key,
+		Failures:      int32(st.Failures),
+		LastFailureAt: st.LastFailureAt.UTC(),
+		LockedUntil:   sql.NullTime{Time: st.LockedUntil.UTC(), Valid: !st.LockedUntil.IsZero()},
+	}); err != nil {
+		return lockout.State{}, err
+	}
+	return st, tx.Commit()
+}
+
+func (s *pgThrottleStore) Reset(ctx context.Context, key string) error {
+	return s.q.ResetLoginThrottle(ctx, key)
+}
+
+// sweep deletes forgotten rows at most once a minute per instance, so
+// guessing random emails can't grow the table forever.
+func (s *pgThrottleStore) sweep(ctx context.Context, now, forgetBefore time.Time) {
+	last := s.lastSweep.Load()
+	if now.Unix()-last < 60 || !s.lastSweep.CompareAndSwap(last, now.Unix()) {
+		return
+	}
+	if err := s.q.DeleteStaleLoginThrottles(ctx, database.DeleteStaleLoginThrottlesParams{
+		ForgetBefore: forgetBefore.UTC(),
+		Now:          now.UTC(),
+	}); err != nil {
+		log.Printf("unable to delete stale login throttles: %v", err)
+	}
+}
+
+func throttleState(row database.LoginThrottle) lockout.State {
+	st := lockout.State{
+		Failures:      int(row.Failures),
+		LastFailureAt: row.LastFailureAt,
+	}
+	if row.LockedUntil.Valid {
+		st.LockedUntil = row.LockedUntil.Time
+	}
+	return st
+}
+
+// throttleKey is one counter a login attempt is charged against.
+// forgiveOnSuccess clears the counter when the attempt succeeds; otherwise
+// the attempt is only given back.
+type throttleKey struct {
+	limiter          *lockout.Limiter
+	key              string
+	forgiveOnSuccess bool
+}
+
+// loginThrottleKeys returns the counters a login attempt for account is
+// charged against. Only the account is forgiven on success: one good
+// password from an address says nothing about the other accounts it has
+// been guessing at.
+func (cfg *apiConfig) loginThrottleKeys(r *http.Request, account string) []throttleKey {
+	return []throttleKey{
+		{cfg.accountThrottle, account, true},
+		{cfg.ipThrottle, "ip:" + cfg.clientIP(r), false},
+	}
+}
+
+// loginAttempt is a login attempt reserved against every throttle key
+// before the credentials are checked, so that a burst of parallel guesses
+// can't all get in before the first of them fails. It has to be settled
+// with failLogin or succeed; release gives it back otherwise.
+type loginAttempt struct {
+	keys     []throttleKey
+	attempts []lockout.Attempt
+	settled  bool
+}
+
+// reserveLogin reserves an attempt against every key. If any of them has to
+// wait it gives back the others, responds with 429 and returns false.
+func reserveLogin(w http.ResponseWriter, r *http.Request, keys []throttleKey) (*loginAttempt, bool) {
+	a := &loginAttempt{keys: keys}
+	for _, k := range keys {
+		res, err := k.limiter.Attempt(r.Context(), k.key)
+		if err != nil {
+			a.release(r.Context())
+			helper.RespondWithError(w, 500, "unable to check login attempts", err)
+			return nil, false
+		}
+		if res.RetryAfter > 0 {
+			a.release(r.Context())
+			setRetryAfter(w, res.RetryAfter)
+			helper.RespondWithError(w, http.StatusTooManyRequests, "too many failed attempts, try again later", nil)
+			return nil, false
+		}
+		a.attempts = append(a.attempts, res)
+	}
+	return a, true
+}
+
+// failLogin settles the attempt as a failure, writes an audit record for any key
+// it locked and sets Retry-After when the next attempt has to wait. userID
+// is the account the attempt was for, if it exists.
+func (cfg *apiConfig) failLogin(w http.ResponseWriter, r *http.Request, a *loginAttempt, userID uuid.NullUUID) {
+	a.settled = true
+
+	var wait time.Duration
+	for i, res := range a.attempts {
+		k := a.keys[i]
+		d, locked, err := k.limiter.Failure(r.Context(), k.key, res)
+		if err != nil {
+			log.Printf("unable to check failed logins for %s: %v", k.key, err)
+			continue
+		}
+		wait = max(wait, d)
+
+		if locked {
+			log.Printf("locked %s for %v after repeated failed logins", k.key, d)
+			err := cfg.dbQueries.CreateLoginLockout(r.Context(), database.CreateLoginLockoutParams{
+				Key:         k.key,
+				UserID:      userID,
+				Ip:          cfg.clientIP(r),
+				LockedUntil: time.Now().UTC().Add(d),
+			})
+			if err != nil {
+				log.Printf("unable to record lockout of %s: %v", k.key, err)
+			}
+		}
+	}
+	if wait > 0 {
+		setRetryAfter(w, wait)
+	}
+}
+
+// succeed settles the attempt as a success.
+func (a *loginAttempt) succeed(ctx context.Context) {
+	a.settled = true
+
+	for i, res := range a.attempts {
+		k := a.keys[i]
+		var err error
+		if k.forgiveOnSuccess {
+			err = k.limiter.Success(ctx, k.key)
+		} else {
+			err = k.limiter.Release(ctx, k.key, res)
+		}
+		if err != nil {
+			log.Printf("unable to reset failed logins for %s: %v", k.key, err)
+		}
+	}
+}
+
+// release gives back an attempt that was never settled, such as one cut
+// short by a server error. It is meant to be deferred.
+func (a *loginAttempt) release(ctx context.Context) {
+	if a.settled {
+		return
+	}
+	a.settled = true
+
+	// The client may be gone, but the attempt still has to be returned.
+	ctx = context.WithoutCancel(ctx)
+	for i, res := range a.attempts {
+		k := a.keys[i]
+		if err := k.limiter.Release(ctx, k.key, res); err != nil {
+			log.Printf("unable to release login attempt for %s: %v", k.key, err)
+		}
+	}
+}
+
+// setRetryAfter sets Retry-After in whole seconds, rounding up so clients
+// that honour it don't come back early.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int64(math.Ceil(d.Seconds()))))
+}
@@ -1,31 +1,50 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
+	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/thetsajeet/chirpy/internal/auth"
 	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/entities"
 	"github.com/thetsajeet/chirpy/internal/helper"
 )
 
 type User struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Handle        string    `json:"handle,omitempty"`
+	Token         string    `json:"token,omitempty"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Badges        []string  `json:"badges"`
+}
+
+// Profile is the public view of a user.
+type Profile struct {
 	ID          uuid.UUID `json:"id"`
 	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	Token       string    `json:"token,omitempty"`
+	Handle      string    `json:"handle,omitempty"`
 	IsChirpyRed bool      `json:"is_chirpy_red"`
+	Badges      []string  `json:"badges"`
 }
 
 func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -36,27 +55,52 @@ func (cfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(params.Password)
+	email, err := parseEmail(params.Email)
+	if err != nil {
+		helper.RespondWithError(w, http.StatusBadRequest, "invalid email address", err)
+		return
+	}
+
+	handle, err := parseHandle(params.Handle)
+	if err != nil {
+		helper.RespondWithError(w, http.StatusBadRequest, "invalid handle", err)
+		return
+	}
+
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
 	if err != nil {
 		helper.RespondWithError(w, 500, "unable to hash password", err)
 		return
 	}
 
 	user, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          params.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
-	if err != nil {
+	if msg, ok := userConflict(err); ok {
+		helper.RespondWithError(w, http.StatusConflict, msg, err)
+		return
+	} else if err != nil {
 		helper.RespondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
 		return
 	}
 
+	// The account exists either way; if the email doesn't go out the user
+	// can ask for another one.
+	if err := cfg.sendEmailVerification(r.Context(), user.ID, user.Email); err != nil {
+		log.Printf("unable to send verification email: %v", err)
+	}
+
 	helper.RespondWithJson(w, http.StatusCreated, User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Handle:        user.Handle.String,
+		IsChirpyRed:   user.IsChirpyRed,
+		Badges:        cfg.badges(r.Context(), user.ID),
 	})
 }
 
@@ -66,10 +110,9 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		Email    string `json:"email"`
 	}
 
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+	type mfaChallenge struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -80,52 +123,144 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dat, err := cfg.dbQueries.LoginUser(r.Context(), p.Email)
-	if err != nil {
-		helper.RespondWithError(w, 401, "Unauthorized", err)
+	email := normalizeEmail(p.Email)
+	accountKey := "account:" + email
+	attempt, ok := reserveLogin(w, r, cfg.loginThrottleKeys(r, accountKey))
+	if !ok {
 		return
 	}
+	defer attempt.release(r.Context())
 
-	if err = auth.CheckPasswordHash(dat.HashedPassword, p.Password); err != nil {
+	// Unknown emails are charged like wrong passwords, so the throttle
+	// doesn't reveal which accounts exist.
+	dat, err := cfg.dbQueries.LoginUser(r.Context(), email)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.failLogin(w, r, attempt, uuid.NullUUID{})
 		helper.RespondWithError(w, 401, "Unauthorized", err)
 		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to find user", err)
+		return
 	}
 
-	token, err := auth.MakeJWT(dat.ID, cfg.JWT_SECRET)
+	needsRehash, err := cfg.passwords.Verify(dat.HashedPassword, p.Password)
 	if err != nil {
-		helper.RespondWithError(w, 500, "unable to create token", err)
+		cfg.failLogin(w, r, attempt, uuid.NullUUID{UUID: dat.ID, Valid: true})
+		helper.RespondWithError(w, 401, "Unauthorized", err)
+		return
+	}
+	if dat.SuspendedAt.Valid {
+		helper.RespondWithError(w, 403, "account suspended", auth.ErrAccountSuspended)
 		return
 	}
+	attempt.succeed(r.Context())
+	if needsRehash {
+		cfg.rehashPassword(r.Context(), dat.ID, p.Password)
+	}
 
-	refreshToken, err := auth.MakeRefreshToken()
+	// With two-factor authentication enabled the password only earns a
+	// short-lived challenge token, exchanged at /api/login/mfa.
+	if dat.TotpEnabledAt.Valid {
+		mfaToken, err := cfg.keys.MakeMFAToken(dat.ID)
+		if err != nil {
+			helper.RespondWithError(w, 500, "unable to create token", err)
+			return
+		}
+		helper.RespondWithJson(w, 200, mfaChallenge{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	cfg.respondWithLogin(w, r, dat)
+}
+
+// rehashPassword upgrades a user's stored hash to the current algorithm and
+// parameters. Login goes ahead even if this fails; it will be retried next
+// time.
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashed, err := cfg.passwords.Hash(password)
+	if err == nil {
+		err = cfg.dbQueries.UpdatePassword(ctx, database.UpdatePasswordParams{
+			ID:             userID,
+			HashedPassword: hashed,
+		})
+	}
 	if err != nil {
-		helper.RespondWithError(w, 500, "unable to create refresh token", err)
+		log.Printf("unable to rehash password: %v", err)
+	}
+}
+
+// respondWithLogin issues an access token and starts a new session for a
+// user who has fully authenticated.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, dat database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	sessionID := uuid.New()
+	token, err := cfg.keys.MakeJWT(dat.ID, sessionID)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create token", err)
 		return
 	}
 
-	err = cfg.dbQueries.StoreRefreshToken(r.Context(), database.StoreRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    dat.ID,
-		ExpiresAt: time.Now().Add(60 * time.Hour * 24),
-	})
+	refreshToken, err := cfg.issueRefreshToken(r, cfg.dbQueries, dat.ID, sessionID)
 	if err != nil {
-		helper.RespondWithError(w, 400, "unable to create refresh token", err)
+		helper.RespondWithError(w, 500, "unable to create refresh token", err)
 		return
 	}
 
 	helper.RespondWithJson(w, 200, response{
 		User: User{
-			ID:          dat.ID,
-			Email:       dat.Email,
-			CreatedAt:   dat.CreatedAt,
-			UpdatedAt:   dat.UpdatedAt,
-			IsChirpyRed: dat.IsChirpyRed,
+			ID:            dat.ID,
+			Email:         dat.Email,
+			EmailVerified: dat.EmailVerifiedAt.Valid,
+			Handle:        dat.Handle.String,
+			CreatedAt:     dat.CreatedAt,
+			UpdatedAt:     dat.UpdatedAt,
+			IsChirpyRed:   dat.IsChirpyRed,
+			Badges:        cfg.badges(r.Context(), dat.ID),
 		},
 		Token:        token,
 		RefreshToken: refreshToken,
 	})
 }
 
+// issueRefreshToken creates and stores a new refresh token in the given token
+// family. Logging in starts a new family; every refresh continues it.
+func (cfg *apiConfig) issueRefreshToken(r *http.Request, q *database.Queries, userID, familyID uuid.UUID) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cfg.storeRefreshToken(r, q, refreshToken, userID, familyID); err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// storeRefreshToken saves a refresh token along with the device that asked
+// for it, which is what the sessions endpoints show to the user.
+func (cfg *apiConfig) storeRefreshToken(r *http.Request, q *database.Queries, refreshToken string, userID, familyID uuid.UUID) error {
+	return q.StoreRefreshToken(r.Context(), database.StoreRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(cfg.REFRESH_TOKEN_TTL),
+		FamilyID:  familyID,
+		UserAgent: r.UserAgent(),
+		Ip:        cfg.clientIP(r),
+	})
+}
+
+// handleRefresh exchanges a refresh token for a new access token and a new
+// refresh token. The presented token is revoked and linked to its
+// replacement; if an already-rotated token is ever presented again it has
+// probably been stolen, so the whole token family is revoked.
 func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -133,25 +268,72 @@ func (cfg *apiConfig) handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dat, err := cfg.dbQueries.LookupToken(r.Context(), refreshToken)
-	if err != nil || dat.ExpiresAt.Compare(time.Now()) <= 0 || (dat.RevokedAt.Valid && dat.RevokedAt.Time.Compare(time.Now()) <= 0) {
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to create refresh token", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to refresh token", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	dat, err := qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		Token:      refreshToken,
+		ReplacedBy: sql.NullString{String: newRefreshToken, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		cfg.detectRefreshTokenReuse(r.Context(), refreshToken)
 		helper.RespondWithError(w, 401, "token expired or not found", err)
 		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to refresh token", err)
+		return
 	}
 
-	token, err := auth.MakeJWT(dat.UserID, cfg.JWT_SECRET)
+	if err := cfg.storeRefreshToken(r, qtx, newRefreshToken, dat.UserID, dat.FamilyID); err != nil {
+		helper.RespondWithError(w, 500, "unable to refresh token", err)
+		return
+	}
+
+	// Sign before committing: if signing fails the presented token must stay
+	// usable, or retrying it would look like reuse and revoke the family.
+	token, err := cfg.keys.MakeJWT(dat.UserID, dat.FamilyID)
 	if err != nil {
 		helper.RespondWithError(w, 500, "unable to make JWT", err)
 		return
 	}
 
-	fmt.Printf("%v", token)
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to refresh token", err)
+		return
+	}
 
 	helper.RespondWithJson(w, 200, map[string]any{
-		"token": token,
+		"token":         token,
+		"refresh_token": newRefreshToken,
 	})
 }
 
+// detectRefreshTokenReuse revokes every token in the family of a refresh token
+// that was already rotated out.
+func (cfg *apiConfig) detectRefreshTokenReuse(ctx context.Context, refreshToken string) {
+	dat, err := cfg.dbQueries.LookupToken(ctx, refreshToken)
+	if err != nil || !dat.ReplacedBy.Valid {
+		return
+	}
+
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s", dat.UserID, dat.FamilyID)
+	if err := cfg.dbQueries.RevokeTokenFamily(ctx, dat.FamilyID); err != nil {
+		log.Printf("Error revoking token family %s: %s", dat.FamilyID, err)
+	}
+}
+
 func (cfg *apiConfig) handleRevoke(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -171,6 +353,7 @@ func (cfg *apiConfig) handleUpdate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 
 	params := parameters{}
@@ -179,74 +362,136 @@ func (cfg *apiConfig) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := auth.GetBearerToken(r.Header)
+	email, err := parseEmail(params.Email)
 	if err != nil {
-		helper.RespondWithError(w, 401, "unauthorized", err)
+		helper.RespondWithError(w, 400, "invalid email address", err)
 		return
 	}
 
-	userId, err := auth.ValidateJWT(accessToken, cfg.JWT_SECRET)
+	handle, err := parseHandle(params.Handle)
 	if err != nil {
-		helper.RespondWithError(w, 401, "unauthorized", err)
+		helper.RespondWithError(w, 400, "invalid handle", err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(params.Password)
+	userId := auth.PrincipalFrom(r.Context()).UserID
+
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
 	if err != nil {
 		helper.RespondWithError(w, 401, "unable to hash password", err)
 		return
 	}
 
+	before, err := cfg.dbQueries.GetUserById(r.Context(), userId)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+
 	dat, err := cfg.dbQueries.UpdateUser(r.Context(), database.UpdateUserParams{
-		Email:          params.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 		ID:             userId,
 	})
-	if err != nil {
+	if msg, ok := userConflict(err); ok {
+		helper.RespondWithError(w, 409, msg, err)
+		return
+	} else if err != nil {
 		helper.RespondWithError(w, 400, "unable to save to db", err)
 		return
 	}
 
+	// A new address has to be verified again before the user can post.
+	if dat.Email != before.Email {
+		if err := cfg.sendEmailVerification(r.Context(), userId, dat.Email); err != nil {
+			log.Printf("unable to send verification email: %v", err)
+		}
+	}
+
 	helper.RespondWithJson(w, 200, User{
-		ID:          userId,
-		CreatedAt:   dat.CreatedAt,
-		UpdatedAt:   dat.UpdatedAt,
-		Email:       dat.Email,
-		IsChirpyRed: dat.IsChirpyRed,
+		ID:            userId,
+		CreatedAt:     dat.CreatedAt,
+		UpdatedAt:     dat.UpdatedAt,
+		Email:         dat.Email,
+		EmailVerified: dat.EmailVerifiedAt.Valid,
+		Handle:        dat.Handle.String,
+		IsChirpyRed:   dat.IsChirpyRed,
+		Badges:        cfg.badges(r.Context(), userId),
 	})
 }
 
-func (cfg *apiConfig) UpgradeUser(w http.ResponseWriter, r *http.Request) {
-	type params struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserId uuid.UUID `json:"user_id"`
-		} `json:"data"`
+func (cfg *apiConfig) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		helper.RespondWithError(w, 400, "invalid user id", err)
+		return
 	}
 
-	if apiKey, err := auth.GetAPIKey(r.Header); err != nil {
-		helper.RespondWithError(w, 401, "something went wrong", err)
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 404, "user not found", err)
 		return
-	} else if apiKey != cfg.POLKA_KEY {
-		helper.RespondWithError(w, 401, "invalid api key", errors.New("invalid api key"))
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to get user", err)
 		return
 	}
 
-	p := params{}
-	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		helper.RespondWithError(w, 400, "unable to decode json", err)
-		return
-	}
+	helper.RespondWithJson(w, 200, Profile{
+		ID:          user.ID,
+		CreatedAt:   user.CreatedAt,
+		Handle:      user.Handle.String,
+		IsChirpyRed: user.IsChirpyRed,
+		Badges:      cfg.badges(r.Context(), user.ID),
+	})
+}
 
-	if p.Event != "user.upgraded" {
-		helper.RespondWithJson(w, 204, map[string]any{})
-		return
+// normalizeEmail puts an address in the form it is stored and looked up in.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// parseEmail normalizes an email address from a request body and checks it is
+// a bare address, without a display name.
+func parseEmail(email string) (string, error) {
+	email = normalizeEmail(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", err
+	}
+	if addr.Address != email {
+		return "", errors.New("expected a bare address such as name@example.com")
 	}
+	return email, nil
+}
 
-	if err := cfg.dbQueries.UpdateChirpyRed(r.Context(), p.Data.UserId); err != nil {
-		helper.RespondWithError(w, 404, "something went wrong", err)
-		return
+// parseHandle validates an optional handle from a request body. An empty
+// handle is left unset.
+func parseHandle(handle string) (sql.NullString, error) {
+	if handle == "" {
+		return sql.NullString{}, nil
 	}
+	normalized, ok := entities.NormalizeHandle(handle)
+	if !ok {
+		return sql.NullString{}, errors.New("handles are 1-15 letters, digits or underscores")
+	}
+	return sql.NullString{String: normalized, Valid: true}, nil
+}
 
-	helper.RespondWithJson(w, 204, map[string]any{})
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// userConflict turns a unique violation on users into a message saying which
+// field is taken.
+func userConflict(err error) (string, bool) {
+	if !isUniqueViolation(err) {
+		return "", false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_email_idx" {
+		return "email is already registered", true
+	}
+	return "handle is already taken", true
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/thetsajeet/chirpy/internal/auth"
+	"github.com/thetsajeet/chirpy/internal/database"
+	"github.com/thetsajeet/chirpy/internal/helper"
+	"github.com/thetsajeet/chirpy/internal/mailer"
+)
+
+// sendEmailVerification emails a link proving the user owns email. The token
+// is tied to the address, so it stops working if the user changes it.
+func (cfg *apiConfig) sendEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.dbQueries.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(cfg.EMAIL_VERIFICATION_TTL),
+	}); err != nil {
+		return err
+	}
+
+	link := cfg.APP_BASE_URL + "/verify-email?token=" + url.QueryEscape(token)
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email for Chirpy",
+		Body: fmt.Sprintf(
+			"Welcome to Chirpy!\n\n"+
+				"Open this link within %s to confirm your email address:\n%s\n\n"+
+				"You won't be able to post until you do.\n",
+			cfg.EMAIL_VERIFICATION_TTL, link,
+		),
+	})
+}
+
+func (cfg *apiConfig) handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		helper.RespondWithError(w, 400, "unable to decode parameters", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify email", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.dbQueries.WithTx(tx)
+
+	verification, err := qtx.UseEmailVerificationToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		helper.RespondWithError(w, 400, "invalid or expired verification token", err)
+		return
+	} else if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify email", err)
+		return
+	}
+
+	n, err := qtx.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if err != nil {
+		helper.RespondWithError(w, 500, "unable to verify email", err)
+		return
+	}
+	if n == 0 {
+		// Either already verified or the address has changed since the link
+		// was sent; only the latter is an error.
+		user, err := qtx.GetUserById(r.Context(), verification.UserID)
+		if err != nil || user.Email != verification.Email {
+			helper.RespondWithError(w, 400, "invalid or expired verification token", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		helper.RespondWithError(w, 500, "unable to verify email", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 204, map[string]any{})
+}
+
+func (cfg *apiConfig) handleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := auth.PrincipalFrom(r.Context()).UserID
+
+	user, err := cfg.dbQueries.GetUserById(r.Context(), userID)
+	if err != nil {
+		helper.RespondWithError(w, 401, "unauthorized", err)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		helper.RespondWithError(w, 409, "email is already verified", nil)
+		return
+	}
+
+	if err := cfg.sendEmailVerification(r.Context(), user.ID, user.Email); err != nil {
+		helper.RespondWithError(w, 500, "unable to send verification email", err)
+		return
+	}
+
+	helper.RespondWithJson(w, 202, map[string]any{})
+}